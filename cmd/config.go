@@ -0,0 +1,170 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"regexp"
+
+	"github.com/3bbbeau/tfvars-atlantis-config/discovery"
+	"github.com/3bbbeau/tfvars-atlantis-config/repocfg"
+	"gopkg.in/yaml.v3"
+)
+
+// Config represents the configuration file passed with `--config`, for
+// settings too structured to be passed as flags, e.g.:
+//
+//	environments:
+//	  - match: ^prod$
+//	    apply_requirements: [approved, mergeable, undiverged]
+//	    autoplan: false
+//	  - match: ^dev$
+//	    branch: ^main$
+//	roots:
+//	  - path: infra/aws
+//	  - path: infra/gcp
+//	    env_strategy: directory
+//	    terraform_version: 1.9.0
+type Config struct {
+	Environments []EnvironmentConfig `yaml:"environments"`
+	Roots        []RootConfig        `yaml:"roots"`
+}
+
+// EnvironmentConfig represents the settings of the projects for the
+// environments matching a regular expression, see repocfg.EnvironmentPolicy.
+type EnvironmentConfig struct {
+	Match              string   `yaml:"match"`
+	ApplyRequirements  []string `yaml:"apply_requirements"`
+	PlanRequirements   []string `yaml:"plan_requirements"`
+	ImportRequirements []string `yaml:"import_requirements"`
+	Autoplan           *bool    `yaml:"autoplan"`
+	Branch             string   `yaml:"branch"`
+}
+
+// RootConfig represents a directory, relative to `--root`, to discover
+// components in, overriding the flags of the same name for its projects.
+type RootConfig struct {
+	Path                  string  `yaml:"path"`
+	Autoplan              *bool   `yaml:"autoplan"`
+	BackendConfigPattern  *string `yaml:"backend_config_pattern"`
+	EnvRegex              *string `yaml:"env_regex"`
+	EnvStrategy           *string `yaml:"env_strategy"`
+	IncludeNoVarFiles     *bool   `yaml:"include_no_var_files"`
+	MaxVarFileDepth       *int    `yaml:"max_var_file_depth"`
+	TerraformDistribution *string `yaml:"terraform_distribution"`
+	TerraformVersion      *string `yaml:"terraform_version"`
+	UseWorkspaces         *bool   `yaml:"use_workspaces"`
+}
+
+// loadConfig reads the configuration file, rejecting unknown fields.
+func loadConfig(name string) (*Config, error) {
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("reading config: %w", err)
+	}
+
+	var cfg Config
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	if err := dec.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("parsing config %s: %w", name, err)
+	}
+	return &cfg, nil
+}
+
+// toPolicies converts the environments of the configuration to policies
+// within the repocfg package
+func (cfg *Config) toPolicies() ([]repocfg.EnvironmentPolicy, error) {
+	var policies []repocfg.EnvironmentPolicy
+
+	for i, env := range cfg.Environments {
+		if env.Match == "" {
+			return nil, fmt.Errorf("environments[%d]: match is required", i)
+		}
+		re, err := regexp.Compile(env.Match)
+		if err != nil {
+			return nil, fmt.Errorf("environments[%d]: match: %w", i, err)
+		}
+		if _, err := regexp.Compile(env.Branch); err != nil {
+			return nil, fmt.Errorf("environments[%d]: branch: %w", i, err)
+		}
+
+		policies = append(policies, repocfg.EnvironmentPolicy{
+			Environment:        re,
+			ApplyRequirements:  env.ApplyRequirements,
+			PlanRequirements:   env.PlanRequirements,
+			ImportRequirements: env.ImportRequirements,
+			Autoplan:           env.Autoplan,
+			Branch:             env.Branch,
+		})
+	}
+
+	return policies, nil
+}
+
+// toRootOptions returns the options for each root of the configuration,
+// overriding those given by the flags.
+func (cfg *Config) toRootOptions(flags *Flags, base discovery.Options) ([]discovery.Options, error) {
+	var roots []discovery.Options
+
+	for i, r := range cfg.Roots {
+		opts, err := r.apply(flags, base)
+		if err != nil {
+			return nil, fmt.Errorf("roots[%d]: %w", i, err)
+		}
+		roots = append(roots, opts)
+	}
+
+	return roots, nil
+}
+
+// apply returns a copy of opts with the overrides of the root.
+func (r RootConfig) apply(flags *Flags, opts discovery.Options) (discovery.Options, error) {
+	if r.Path == "" {
+		return opts, fmt.Errorf("path is required")
+	}
+	opts.Root = path.Join(opts.Root, r.Path)
+
+	if r.EnvStrategy != nil || r.EnvRegex != nil {
+		name, expr := flags.EnvStrategy, flags.EnvRegex
+		if r.EnvStrategy != nil {
+			name = *r.EnvStrategy
+		}
+		if r.EnvRegex != nil {
+			expr = *r.EnvRegex
+		}
+		strategy, err := discovery.NewStrategy(name, expr)
+		if err != nil {
+			return opts, err
+		}
+		opts.Strategy = strategy
+	}
+
+	if r.BackendConfigPattern != nil {
+		opts.BackendConfigPattern = *r.BackendConfigPattern
+	}
+	if r.MaxVarFileDepth != nil {
+		opts.MaxVarFileDepth = *r.MaxVarFileDepth
+	}
+	if r.Autoplan != nil {
+		opts.RepoCfg.Autoplan = *r.Autoplan
+	}
+	if r.IncludeNoVarFiles != nil {
+		opts.RepoCfg.IncludeNoVarFiles = *r.IncludeNoVarFiles
+	}
+	if r.TerraformDistribution != nil {
+		opts.RepoCfg.TerraformDistribution = *r.TerraformDistribution
+	}
+	if r.TerraformVersion != nil {
+		opts.RepoCfg.DefaultTerraformVersion = *r.TerraformVersion
+		opts.RepoCfg.DefaultTerraformVersionSource = fmt.Sprintf("terraform_version of root %s", r.Path)
+	}
+	if r.UseWorkspaces != nil {
+		opts.RepoCfg.UseWorkspaces = *r.UseWorkspaces
+	}
+
+	return opts, nil
+}
@@ -0,0 +1,183 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/3bbbeau/tfvars-atlantis-config/discovery"
+	"github.com/3bbbeau/tfvars-atlantis-config/repocfg"
+	"github.com/google/go-cmp/cmp"
+)
+
+// Tests the config file is loaded and converted to policies, and invalid
+// configs are rejected.
+func Test_LoadConfig(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		config  string
+		want    int
+		wantErr bool
+	}{
+		{
+			name: "Environments",
+			config: `environments:
+  - match: ^prod$
+    apply_requirements: [approved, mergeable, undiverged]
+    autoplan: false
+  - match: ^dev$
+    branch: ^main$
+`,
+			want: 2,
+		},
+		{
+			name:    "UnknownField",
+			config:  "environments:\n  - match: dev\n    automerge: true\n",
+			wantErr: true,
+		},
+		{
+			name:    "MissingMatch",
+			config:  "environments:\n  - autoplan: false\n",
+			wantErr: true,
+		},
+		{
+			name:    "InvalidMatch",
+			config:  "environments:\n  - match: \"(\"\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			name := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(name, []byte(tc.config), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, err := loadConfig(name)
+			if err == nil {
+				var policies []repocfg.EnvironmentPolicy
+				policies, err = cfg.toPolicies()
+				if err == nil && len(policies) != tc.want {
+					t.Errorf("toPolicies() got %d policies, want %d", len(policies), tc.want)
+				}
+			}
+			if (err != nil) != tc.wantErr {
+				t.Errorf("loadConfig() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+// Tests each root of the config file overrides the options given by the
+// flags, and invalid roots are rejected.
+func Test_ToRootOptions(t *testing.T) {
+	t.Parallel()
+
+	flags, err := NewFlags()
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := discovery.Options{
+		Strategy:        discovery.FilenameStrategy{},
+		MaxVarFileDepth: 2,
+		RepoCfg:         repocfg.Options{Autoplan: true, DefaultTerraformVersion: "1.8.0"},
+	}
+
+	cfg := &Config{Roots: []RootConfig{
+		{Path: "infra/aws"},
+		{
+			Path:             "infra/gcp",
+			EnvStrategy:      ptr(discovery.DIRECTORY_STRATEGY),
+			Autoplan:         ptr(false),
+			MaxVarFileDepth:  ptr(0),
+			TerraformVersion: ptr("1.9.0"),
+		},
+	}}
+
+	got, err := cfg.toRootOptions(flags, base)
+	if err != nil {
+		t.Fatalf("toRootOptions() error: %s", err)
+	}
+
+	want := []discovery.Options{
+		{
+			Root:            "infra/aws",
+			Strategy:        discovery.FilenameStrategy{},
+			MaxVarFileDepth: 2,
+			RepoCfg:         repocfg.Options{Autoplan: true, DefaultTerraformVersion: "1.8.0"},
+		},
+		{
+			Root:     "infra/gcp",
+			Strategy: discovery.DirectoryStrategy{},
+			RepoCfg:  repocfg.Options{DefaultTerraformVersion: "1.9.0", DefaultTerraformVersionSource: "terraform_version of root infra/gcp"},
+		},
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf(`toRootOptions()
+		diff %s`, diff)
+	}
+
+	for _, r := range []RootConfig{
+		{},
+		{Path: "infra", EnvStrategy: ptr("unknown")},
+	} {
+		cfg := &Config{Roots: []RootConfig{r}}
+		if _, err := cfg.toRootOptions(flags, base); err == nil {
+			t.Errorf("toRootOptions() expected error for %+v", r)
+		}
+	}
+}
+
+// Tests the flags' root options carry the policies of the config file to each
+// of its roots.
+func Test_FlagsToRootOptions(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	name := filepath.Join(dir, "config.yaml")
+	config := `environments:
+  - match: ^prd$
+    apply_requirements: [approved]
+roots:
+  - path: aws
+  - path: gcp
+`
+	if err := os.WriteFile(name, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	flags, err := NewFlags()
+	if err != nil {
+		t.Fatal(err)
+	}
+	flags.Root = dir
+	flags.Config = name
+
+	roots, err := flags.toRootOptions(nil)
+	if err != nil {
+		t.Fatalf("toRootOptions() error: %s", err)
+	}
+
+	var got []string
+	for _, r := range roots {
+		for _, p := range r.RepoCfg.Policies {
+			got = append(got, r.Root+" "+p.Environment.String())
+		}
+	}
+	want := []string{"aws ^prd$", "gcp ^prd$"}
+	if !cmp.Equal(got, want) {
+		t.Errorf(`toRootOptions()
+		diff %s`, cmp.Diff(got, want))
+	}
+}
+
+// ptr returns a pointer to v.
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/3bbbeau/tfvars-atlantis-config/discovery"
+	"github.com/3bbbeau/tfvars-atlantis-config/logger"
+	"github.com/spf13/cobra"
+)
+
+// NewDiscoverCmd creates a new `discover` command, while applying the
+// discovery flags with their defaults overlayed by the flags passed in by the
+// caller.
+func NewDiscoverCmd() (*cobra.Command, error) {
+	flags, err := NewFlags()
+	if err != nil {
+		return nil, fmt.Errorf("new flags: %w", err)
+	}
+	explain := false
+
+	cmd := &cobra.Command{
+		Use:   "discover",
+		Short: "Lists the Terraform components and var files found",
+		Long: `Lists the Terraform components and the var files of each environment that
+		generate would make Atlantis config for.
+
+		With --explain, lists every var file found, which component claimed it and why.
+		A var file is claimed by the nearest directory at or above it containing a .tf,
+		.tf.json, .tofu or .tofu.json file.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			roots, err := flags.toRootOptions(logger.FromContext(cmd.Context()))
+			if err != nil {
+				return err
+			}
+			fsys, _, err := flags.repo()
+			if err != nil {
+				return err
+			}
+
+			if explain {
+				claims, err := discovery.ExplainRoots(cmd.Context(), fsys, roots)
+				if err != nil {
+					return err
+				}
+				return writeClaims(cmd.OutOrStdout(), claims)
+			}
+
+			components, err := discovery.DiscoverRoots(cmd.Context(), fsys, roots)
+			if err != nil {
+				return err
+			}
+			for _, c := range components {
+				fmt.Fprintln(cmd.OutOrStdout(), c.Path)
+				for _, v := range c.VarFiles {
+					fmt.Fprintf(cmd.OutOrStdout(), "  %s: %s\n", v.Environment, v.Path)
+				}
+			}
+			return nil
+		},
+	}
+
+	flags.AddDiscoveryFlags(cmd)
+	cmd.Flags().BoolVar(&explain, "explain", explain, "List each var file with the component that claimed it and why. Default is disabled")
+
+	return cmd, nil
+}
+
+// writeClaims writes one line per var file describing its claim, e.g.:
+//
+//	component/dev.tfvars: claimed by component (dev): component in the same directory
+//	orphan/dev.tfvars: unclaimed: no component in its directory or any parent directory
+func writeClaims(w io.Writer, claims []discovery.Claim) error {
+	for _, c := range claims {
+		var err error
+		if c.Claimed() {
+			_, err = fmt.Fprintf(w, "%s: claimed by %s (%s): %s\n", c.VarFile, c.Component, c.Environment, c.Reason)
+		} else {
+			_, err = fmt.Fprintf(w, "%s: unclaimed: %s\n", c.VarFile, c.Reason)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
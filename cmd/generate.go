@@ -1,30 +1,60 @@
 package cmd
 
 import (
-	"context"
+	"bufio"
+	"bytes"
 	"fmt"
+	"io"
 	"os"
+	"path"
 	"path/filepath"
-	"slices"
 	"strings"
+	"time"
 
+	"github.com/3bbbeau/tfvars-atlantis-config/discovery"
 	"github.com/3bbbeau/tfvars-atlantis-config/logger"
 	"github.com/3bbbeau/tfvars-atlantis-config/repocfg"
+	"github.com/runatlantis/atlantis/server/core/config"
+	"github.com/runatlantis/atlantis/server/core/config/valid"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
-	"gopkg.in/yaml.v2"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
 // Flags represents the flags for the `generate` command
 type Flags struct {
-	AutoMerge               bool
-	AutoPlan                bool
-	DefaultTerraformVersion string
-	MultiEnv                bool
-	Output                  string
-	Parallel                bool
-	Root                    string
-	UseWorkspaces           bool
+	AbortOnExecutionOrderFail bool
+	AllowedRegexpPrefixes     []string
+	AutoDiscoverMode          string
+	AutoMerge                 bool
+	AutoPlan                  bool
+	BackendConfigPattern      string
+	ChangedFiles              string
+	ComponentDistributions    map[string]string
+	Concurrency               int
+	Config                    string
+	CreateDirs                bool
+	DefaultTerraformVersion   string
+	DeleteSourceBranchOnMerge bool
+	EmojiReaction             string
+	EnvRegex                  string
+	EnvStrategy               string
+	IncludeNoVarFiles         bool
+	MaxVarFileDepth           int
+	MultiEnv                  bool
+	Output                    string
+	Parallel                  bool
+	ParallelPolicyCheck       bool
+	PathPrefix                string
+	ProvenanceComments        bool
+	RepoID                    string
+	RepoLocksMode             string
+	Report                    string
+	Root                      string
+	ServerConfig              string
+	TerraformDistribution     string
+	UseWorkspaces             bool
 }
 
 // NewFlags returns a default Flags struct
@@ -35,40 +65,231 @@ func NewFlags() (*Flags, error) {
 	}
 
 	return &Flags{
-		AutoMerge:               false,
-		AutoPlan:                false,
-		DefaultTerraformVersion: "",
-		Root:                    pwd,
-		Output:                  "",
-		Parallel:                false,
-		UseWorkspaces:           false,
+		AbortOnExecutionOrderFail: false,
+		AllowedRegexpPrefixes:     []string{},
+		AutoDiscoverMode:          "",
+		AutoMerge:                 false,
+		AutoPlan:                  false,
+		BackendConfigPattern:      "",
+		ChangedFiles:              "",
+		ComponentDistributions:    map[string]string{},
+		Concurrency:               0,
+		Config:                    "",
+		CreateDirs:                false,
+		DefaultTerraformVersion:   "",
+		DeleteSourceBranchOnMerge: false,
+		EmojiReaction:             "",
+		EnvRegex:                  "",
+		EnvStrategy:               discovery.FILENAME_STRATEGY,
+		IncludeNoVarFiles:         false,
+		MaxVarFileDepth:           0,
+		Output:                    "",
+		Parallel:                  false,
+		ParallelPolicyCheck:       false,
+		PathPrefix:                "",
+		ProvenanceComments:        false,
+		RepoID:                    "",
+		RepoLocksMode:             "",
+		Report:                    "",
+		Root:                      pwd,
+		ServerConfig:              "",
+		TerraformDistribution:     "",
+		UseWorkspaces:             false,
 	}, nil
 }
 
 // AddFlags registers flags for the `generate` command
 func (flags *Flags) AddFlags(cmd *cobra.Command) {
+	flags.AddDiscoveryFlags(cmd)
+	flags.AddRepoCfgFlags(cmd)
+	cmd.Flags().StringVar(&flags.Output, "output", flags.Output, "Path of the file where configuration will be generated. Default is stdout")
+	cmd.Flags().BoolVar(&flags.CreateDirs, "create-dirs", flags.CreateDirs, "Create the parent directories of --output if they do not exist. Default is disabled")
+	cmd.Flags().StringVar(&flags.Report, "report", flags.Report, "Path of a JSON file where a report of the run is written, with the components, projects, ignored var files, warnings and timings. Default is none")
+	cmd.Flags().BoolVar(&flags.ProvenanceComments, "provenance-comments", flags.ProvenanceComments, "Comment each project with the component and var file it was generated from. Default is disabled")
+}
+
+// AddRepoCfgFlags registers the flags controlling the Atlantis projects
+// generated from the discovered components, shared by the commands that
+// generate them.
+func (flags *Flags) AddRepoCfgFlags(cmd *cobra.Command) {
 	cmd.Flags().BoolVar(&flags.AutoPlan, "autoplan", flags.AutoPlan, "Enable auto plan. Default is disabled")
+	cmd.Flags().StringVar(&flags.ChangedFiles, "changed-files", flags.ChangedFiles, "Path of a file listing changed files relative to the repo root, one per line, or - for stdin. Only projects affected by them are generated. Default is all projects")
 	cmd.Flags().BoolVar(&flags.AutoMerge, "automerge", flags.AutoMerge, "Enable auto merge. Default is disabled")
+	cmd.Flags().BoolVar(&flags.IncludeNoVarFiles, "include-no-var-files", flags.IncludeNoVarFiles, "Generate a default workspace project for components without var files. Default is disabled")
 	cmd.Flags().BoolVar(&flags.Parallel, "parallel", flags.Parallel, "Enables plans and applys to happen in parallel. Default is disabled")
-	cmd.Flags().StringVar(&flags.Output, "output", flags.Output, "Path of the file where configuration will be generated. Default is stdout")
-	cmd.Flags().StringVar(&flags.Root, "root", flags.Root, "Path to the root directory of the git repo you want to build config for. Default is current dir")
+	cmd.Flags().StringVar(&flags.TerraformDistribution, "terraform-distribution", flags.TerraformDistribution, "Terraform distribution Atlantis runs for projects: terraform or opentofu. Default is Atlantis' default")
+	cmd.Flags().StringToStringVar(&flags.ComponentDistributions, "component-distribution", flags.ComponentDistributions, "Terraform distribution for the projects of a component, overriding --terraform-distribution, e.g. path/to/component=opentofu")
 	cmd.Flags().StringVar(&flags.DefaultTerraformVersion, "terraform-version", flags.DefaultTerraformVersion, "Default terraform version to run for Atlantis. Default is determined by the Terraform version constraints.")
 	cmd.Flags().BoolVar(&flags.UseWorkspaces, "use-workspaces", flags.UseWorkspaces, "Use workspaces for projects. Default is disabled")
+	cmd.Flags().BoolVar(&flags.AbortOnExecutionOrderFail, "abort-on-execution-order-fail", flags.AbortOnExecutionOrderFail, "Abort applies of later execution order groups if one fails. Default is disabled")
+	cmd.Flags().StringSliceVar(&flags.AllowedRegexpPrefixes, "allowed-regexp-prefixes", flags.AllowedRegexpPrefixes, "Prefixes a project name regexp in a comment command must start with. Default is none")
+	cmd.Flags().StringVar(&flags.AutoDiscoverMode, "autodiscover-mode", flags.AutoDiscoverMode, "Atlantis autodiscover mode: auto, enabled or disabled. Default is Atlantis' default")
+	cmd.Flags().BoolVar(&flags.DeleteSourceBranchOnMerge, "delete-source-branch-on-merge", flags.DeleteSourceBranchOnMerge, "Delete the source branch of pull requests automerged by Atlantis. Default is disabled")
+	cmd.Flags().StringVar(&flags.EmojiReaction, "emoji-reaction", flags.EmojiReaction, "Emoji Atlantis reacts to comment commands with. Default is Atlantis' default")
+	cmd.Flags().BoolVar(&flags.ParallelPolicyCheck, "parallel-policy-check", flags.ParallelPolicyCheck, "Enables policy checks to happen in parallel. Default is disabled")
+	cmd.Flags().StringVar(&flags.RepoLocksMode, "repo-locks-mode", flags.RepoLocksMode, "When Atlantis locks projects: on_plan, on_apply or disabled. Default is Atlantis' default")
+	cmd.Flags().StringVar(&flags.ServerConfig, "server-config", flags.ServerConfig, "Path of the Atlantis server side repo config to validate the generated config against. Default allows every repo setting")
+	cmd.Flags().StringVar(&flags.RepoID, "repo-id", flags.RepoID, "ID of the repo in the server side repo config, e.g. github.com/org/repo")
+}
+
+// AddDiscoveryFlags registers the flags controlling how Terraform components
+// and their var files are discovered, shared by the commands that discover
+// them.
+func (flags *Flags) AddDiscoveryFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&flags.BackendConfigPattern, "backend-config-pattern", flags.BackendConfigPattern, "Path pattern, relative to each component, of per-environment backend config files, e.g. backend/{env}.hcl. Default is disabled")
+	cmd.Flags().IntVar(&flags.Concurrency, "concurrency", flags.Concurrency, "Maximum number of components inspected at once. Default is the number of CPUs")
+	cmd.Flags().StringVar(&flags.Config, "config", flags.Config, "Path of a YAML file with per-environment policies and roots. Default is none")
+	cmd.Flags().StringVar(&flags.EnvStrategy, "env-strategy", flags.EnvStrategy, "How environment names are derived from var files: filename (dev.tfvars), directory (envs/dev/terraform.tfvars) or regex")
+	cmd.Flags().StringVar(&flags.EnvRegex, "env-regex", flags.EnvRegex, "Regular expression matched against var file paths relative to their component for the regex env strategy. The environment is the \"env\" named group, or the first group")
+	cmd.Flags().IntVar(&flags.MaxVarFileDepth, "max-var-file-depth", flags.MaxVarFileDepth, "Maximum number of directories a var file may be nested below its component. Default is no limit")
+	cmd.Flags().StringVar(&flags.PathPrefix, "path-prefix", flags.PathPrefix, "Path of --root relative to the repo root, prepended to the dirs of projects. Default is found from the enclosing git repo")
+	cmd.Flags().StringVar(&flags.Root, "root", flags.Root, "Path to the directory within the git repo you want to build config for. Default is current dir")
 
 	cmd.Flags().VisitAll(func(f *pflag.Flag) {
-		logger.FromContext(cmd.Context()).Sugar().Debugf("Set flag: %s = %v", f.Name, f.Value)
+		logger.FromContext(cmd.Context()).Debug("set flag", zap.String("flag", f.Name), zap.Stringer("value", f.Value))
 	})
 }
 
-// toOptions converts the flags provided for usage to Options within the repocfg package
-func (flags *Flags) toOptions() repocfg.Options {
-	return repocfg.Options{
+// toOptions converts the flags provided for usage, and the policies of the
+// config file if any, to Options within the repocfg package
+func (flags *Flags) toOptions(cfg *Config) (repocfg.Options, error) {
+	opts := repocfg.Options{
 		Automerge:               flags.AutoMerge,
 		Autoplan:                flags.AutoPlan,
 		DefaultTerraformVersion: flags.DefaultTerraformVersion,
+		IncludeNoVarFiles:       flags.IncludeNoVarFiles,
 		Parallel:                flags.Parallel,
 		UseWorkspaces:           flags.UseWorkspaces,
+		TerraformDistribution:   flags.TerraformDistribution,
+		ComponentDistributions:  flags.ComponentDistributions,
+
+		AbortOnExecutionOrderFail: flags.AbortOnExecutionOrderFail,
+		AllowedRegexpPrefixes:     flags.AllowedRegexpPrefixes,
+		AutoDiscoverMode:          flags.AutoDiscoverMode,
+		DeleteSourceBranchOnMerge: flags.DeleteSourceBranchOnMerge,
+		EmojiReaction:             flags.EmojiReaction,
+		ParallelPolicyCheck:       flags.ParallelPolicyCheck,
+		RepoLocksMode:             flags.RepoLocksMode,
+		ProvenanceComments:        flags.ProvenanceComments,
+	}
+	if flags.DefaultTerraformVersion != "" {
+		opts.DefaultTerraformVersionSource = "--terraform-version"
+	}
+
+	if cfg != nil {
+		policies, err := cfg.toPolicies()
+		if err != nil {
+			return opts, fmt.Errorf("config %s: %w", flags.Config, err)
+		}
+		opts.Policies = policies
+	}
+
+	if flags.ServerConfig != "" {
+		parser := &config.ParserValidator{}
+		globalCfg, err := parser.ParseGlobalCfg(flags.ServerConfig, valid.NewGlobalCfgFromArgs(valid.GlobalCfgArgs{}))
+		if err != nil {
+			return opts, fmt.Errorf("server config %s: %w", flags.ServerConfig, err)
+		}
+		opts.GlobalCfg = &globalCfg
+		opts.RepoID = flags.RepoID
+	}
+
+	if flags.ChangedFiles != "" {
+		changed, err := readChangedFiles(flags.ChangedFiles)
+		if err != nil {
+			return opts, err
+		}
+		opts.ChangedFiles = changed
+	}
+
+	return opts, nil
+}
+
+// toDiscoveryOptions converts the flags provided for usage, and the config
+// file if any, to Options within the discovery package
+func (flags *Flags) toDiscoveryOptions(logger *zap.Logger, cfg *Config) (discovery.Options, error) {
+	strategy, err := discovery.NewStrategy(flags.EnvStrategy, flags.EnvRegex)
+	if err != nil {
+		return discovery.Options{}, err
 	}
+
+	repoCfgOpts, err := flags.toOptions(cfg)
+	if err != nil {
+		return discovery.Options{}, err
+	}
+
+	_, root, err := flags.repo()
+	if err != nil {
+		return discovery.Options{}, err
+	}
+
+	return discovery.Options{
+		Strategy:             strategy,
+		BackendConfigPattern: flags.BackendConfigPattern,
+		MaxVarFileDepth:      flags.MaxVarFileDepth,
+		Concurrency:          flags.Concurrency,
+		RepoCfg:              repoCfgOpts,
+		Logger:               logger,
+		Root:                 root,
+	}, nil
+}
+
+// toRootOptions returns the Options for each root of the configuration file,
+// or for the whole of `--root` if it has none. The configuration file is
+// only read once, for both its policies and its roots.
+func (flags *Flags) toRootOptions(logger *zap.Logger) ([]discovery.Options, error) {
+	var cfg *Config
+	if flags.Config != "" {
+		var err error
+		cfg, err = loadConfig(flags.Config)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	opts, err := flags.toDiscoveryOptions(logger, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if cfg == nil || len(cfg.Roots) == 0 {
+		return []discovery.Options{opts}, nil
+	}
+
+	roots, err := cfg.toRootOptions(flags, opts)
+	if err != nil {
+		return nil, fmt.Errorf("config %s: %w", flags.Config, err)
+	}
+	return roots, nil
+}
+
+// readChangedFiles reads the list of changed files, one per line, from the
+// named file or stdin if "-". Paths are slash separated, and blank lines are
+// ignored.
+func readChangedFiles(name string) ([]string, error) {
+	r := io.Reader(os.Stdin)
+	if name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return nil, fmt.Errorf("reading changed files: %w", err)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	changed := []string{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		changed = append(changed, path.Clean(filepath.ToSlash(line)))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading changed files: %w", err)
+	}
+
+	return changed, nil
 }
 
 // NewGenerateCmd creates a new `generate` command, while applying all flags
@@ -100,165 +321,122 @@ func NewGenerateCmd() (*cobra.Command, error) {
 // generate is used to generate an Atlantis config, called from the `generate`
 // command, creating an Atlantis configuration written to flags.OutputPath
 func generate(cmd *cobra.Command, flags *Flags) error {
-	logger := logger.FromContext(cmd.Context())
+	start := time.Now()
+
+	// Warnings are recorded for the report, as well as logged
+	recorder := newWarningRecorder()
+	logger := logger.FromContext(cmd.Context()).WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return zapcore.NewTee(core, recorder)
+	}))
+
+	roots, err := flags.toRootOptions(logger)
+	if err != nil {
+		return err
+	}
 
-	tree, err := discover(cmd.Context(), flags)
+	fsys, root, err := flags.repo()
 	if err != nil {
 		return err
 	}
 
-	opts := flags.toOptions()
-	cfg, err := repocfg.NewRepoCfg(tree, opts)
+	result, err := discovery.Run(cmd.Context(), fsys, roots)
 	if err != nil {
 		return err
 	}
+	generated := time.Now()
 
-	cfgBytes, err := yaml.Marshal(&cfg)
+	// With --path-prefix, --root is the checkout the prefix is within
+	if flags.PathPrefix != "" {
+		root = "."
+	}
+
+	cfg := result.Config
+	cfg.Header = header(cmd, root)
+
+	cfgBytes, err := cfg.Marshal()
 	if err != nil {
 		return fmt.Errorf("repocfg: %w", err)
 	}
 
 	switch flags.Output {
 	case "":
-		fmt.Println(string(cfgBytes))
+		fmt.Fprintln(cmd.OutOrStdout(), string(cfgBytes))
 	default:
-		logger.Sugar().Debugf("writing config to %s", flags.Output)
-		err := os.WriteFile(flags.Output, cfgBytes, 0o644)
+		written, err := writeFileAtomic(flags.Output, cfgBytes, 0o644, flags.CreateDirs)
 		if err != nil {
 			return err
 		}
+		if written {
+			logger.Debug("wrote config", zap.String("file", flags.Output))
+		} else {
+			logger.Debug("config is up to date", zap.String("file", flags.Output))
+		}
 	}
 
-	return nil
-}
-
-const (
-	TF_EXT          = ".tf"
-	TFVARS_EXT      = ".tfvars"
-	TFVARS_JSON_EXT = ".tfvars.json"
-)
-
-// discover walks the directory tree and creates a slice of Terraform components
-// and their dependencies based on the .tfvars files in the directory and
-// subdirectories.
-//
-// However, if nested directories contain a .tf file, the .tfvars files in the
-// subdirectories are ignored, as they are assumed to be part of a different
-// parent node (Terraform component).
-//
-// For example, if the function is invoked at the path defined by the `root` flag:
-//
-//	.
-//	├── components
-//	│   ├── component1
-//	│   │   ├── main.tf
-//	│   │   └── dev.tfvars
-//	│   └── component2
-//	│       ├── extraVars
-//	│       │   └── stg.tfvars
-//	│       ├── main.tf
-//	│       └── dev.tfvars
-//
-// Then, the resulting slice of components would look like:
-//
-//	Path:	"components/component1"
-//	VarFiles:	"components/component1/dev.tfvars"
-//
-//	Path:	"components/component2"
-//	VarFiles:	[]string{"components/component2/dev.tfvars", "components/component2/extraVars/stg.tfvars"}
-func discover(ctx context.Context, flags *Flags) ([]repocfg.Component, error) {
-	logger := logger.FromContext(ctx)
+	report := newReport(result, recorder.All())
+	report.Timings = Timings{
+		Generate: seconds(generated.Sub(start)),
+		Write:    seconds(time.Since(generated)),
+		Total:    seconds(time.Since(start)),
+	}
 
-	discovered := []repocfg.Component{}
+	if err := writeSummary(cmd.ErrOrStderr(), report); err != nil {
+		return err
+	}
 
-	// Walk the directory tree from the root
-	err := filepath.Walk(flags.Root, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
+	if flags.Report != "" {
+		var b bytes.Buffer
+		if err := writeReport(&b, report); err != nil {
+			return fmt.Errorf("report: %w", err)
 		}
-
-		// Each parent should be a path containing a Terraform component
-		if !info.IsDir() && strings.HasSuffix(info.Name(), TF_EXT) {
-			logger.Debug(fmt.Sprintf("found .tf file: %s", path))
-
-			// Each edge should be a path containing Terraform variables files
-			// relative to the Terraform component
-			err = filepath.Walk(filepath.Dir(path), func(subpath string, subinfo os.FileInfo, suberr error) error {
-				if suberr != nil {
-					return suberr
-				}
-
-				// Filter out Terraform variable files
-				if !subinfo.IsDir() && (strings.HasSuffix(subinfo.Name(), TFVARS_EXT) || strings.HasSuffix(subinfo.Name(), TFVARS_JSON_EXT)) {
-					parent := subpath
-					// Ignore nested Terraform variable files that might belong to nested components
-					if filepath.Dir(subpath) != filepath.Dir(path) && !tfExistsInDir(filepath.Dir(subpath)) {
-						logger.Sugar().Debugf(`ignoring nested %s: %s
-						which belongs to the component at: %s
-						not: %s`, filepath.Ext(subpath), subpath, filepath.Dir(subpath), filepath.Dir(path),
-						)
-						parent = path
-					}
-
-					relParent, err := filepath.Rel(flags.Root, filepath.Dir(parent))
-					if err != nil {
-						return err
-					}
-					logger.Sugar().Debugf("component parent is %s", relParent)
-					found := repocfg.Component{
-						Path: relParent,
-					}
-
-					logger.Sugar().Debugf("found %s file: %s", filepath.Ext(subpath), subpath)
-					child, err := filepath.Rel(flags.Root, subpath)
-					if err != nil {
-						return err
-					}
-
-					// VarFile is a member of this component
-					logger.Sugar().Debugf("component %s has var file %s", parent, child)
-					found.VarFiles = append(found.VarFiles, child)
-
-					// Check if the component already exists in the slice
-					// If it does, ensure the var file is not a duplicate and append it.
-					exists := false
-					for idx, component := range discovered {
-						if component.Path == found.Path {
-							exists = true
-							for _, varFile := range found.VarFiles {
-								if !slices.Contains(component.VarFiles, varFile) {
-									discovered[idx].VarFiles = append(discovered[idx].VarFiles, varFile)
-								}
-							}
-						}
-					}
-
-					// If the component does not exist in the slice, then
-					// it can be treated as new component.
-					if !exists {
-						discovered = append(discovered, found)
-					}
-				}
-
-				return err
-			})
-			return filepath.SkipDir
+		if _, err := writeFileAtomic(flags.Report, b.Bytes(), 0o644, flags.CreateDirs); err != nil {
+			return err
 		}
-		return nil
-	})
-	if err != nil {
-		return nil, err
 	}
 
-	return discovered, nil
+	return nil
 }
 
-// tfExistsInDir returns true if a directory contains a Terraform file
-func tfExistsInDir(dir string) bool {
-	files, err := filepath.Glob(filepath.Join(dir, "*"+TF_EXT))
-	if err != nil {
-		return false
+// headerOmittedFlags are the flags left out of the command in the header, as
+// they are specific to a run or a machine rather than to the config, e.g.
+// --changed-files generates a partial config.
+var headerOmittedFlags = map[string]bool{
+	"changed-files": true,
+	"create-dirs":   true,
+	"debug":         true,
+	"log-file":      true,
+	"log-format":    true,
+	"log-level":     true,
+	"output":        true,
+	"report":        true,
+	"root":          true,
+}
+
+// header returns the comment written at the top of the generated config,
+// naming the version of the utility and the flags it was run with, so that it
+// is the same on every machine, e.g.:
+//
+//	Code generated by tfvars-atlantis-config v1.2.3. DO NOT EDIT.
+//	Changes will be overwritten, edit the Terraform components and var files
+//	instead, and regenerate from the repo root with:
+//	  tfvars-atlantis-config generate --autoplan=true --root=infra
+//
+// The root is given relative to the repo root, and left out if it is the repo
+// root itself.
+func header(cmd *cobra.Command, root string) string {
+	command := cmd.CommandPath()
+	cmd.Flags().Visit(func(f *pflag.Flag) {
+		if headerOmittedFlags[f.Name] {
+			return
+		}
+		command += fmt.Sprintf(" --%s=%s", f.Name, f.Value)
+	})
+	if root != "." {
+		command += fmt.Sprintf(" --root=%s", root)
 	}
 
-	return len(files) > 0
+	return fmt.Sprintf(`Code generated by %s %s. DO NOT EDIT.
+Changes will be overwritten, edit the Terraform components and var files
+instead, and regenerate from the repo root with:
+  %s`, cmd.Root().Name(), v, command)
 }
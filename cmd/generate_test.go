@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"testing"
+)
+
+// Tests the header of the generated config names the version and the flags
+// the command was run with, leaving out those specific to a run or a machine.
+func Test_Header(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		args []string
+		root string
+		want string
+	}{
+		{
+			name: "Flags",
+			args: []string{"--autoplan", "--env-strategy", "directory"},
+			root: ".",
+			want: "generate --autoplan=true --env-strategy=directory",
+		},
+		{
+			name: "RunSpecificFlags",
+			args: []string{
+				"--autoplan",
+				"--changed-files", "/tmp/changed.txt",
+				"--create-dirs",
+				"--output", "/home/ci/repo/atlantis.yaml",
+				"--report", "/tmp/report.json",
+				"--root", "/home/ci/repo/infra",
+			},
+			root: "infra",
+			want: "generate --autoplan=true --root=infra",
+		},
+	}
+
+	for _, tc := range tests {
+		cmd, err := NewGenerateCmd()
+		if err != nil {
+			t.Fatalf("NewGenerateCmd() error: %s", err)
+		}
+		if err := cmd.ParseFlags(tc.args); err != nil {
+			t.Fatalf("%s: ParseFlags() error: %s", tc.name, err)
+		}
+
+		want := `Code generated by generate devel. DO NOT EDIT.
+Changes will be overwritten, edit the Terraform components and var files
+instead, and regenerate from the repo root with:
+  ` + tc.want
+
+		if got := header(cmd, tc.root); got != want {
+			t.Errorf(`%s: header()
+			got %s
+			want %s`, tc.name, got, want)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/3bbbeau/tfvars-atlantis-config/discovery"
+	"github.com/3bbbeau/tfvars-atlantis-config/graph"
+	"github.com/3bbbeau/tfvars-atlantis-config/logger"
+	"github.com/spf13/cobra"
+)
+
+const (
+	DOT_FORMAT     = "dot"
+	MERMAID_FORMAT = "mermaid"
+)
+
+// NewGraphCmd creates a new `graph` command, while applying the discovery
+// flags with their defaults overlayed by the flags passed in by the caller.
+func NewGraphCmd() (*cobra.Command, error) {
+	flags, err := NewFlags()
+	if err != nil {
+		return nil, fmt.Errorf("new flags: %w", err)
+	}
+	format := DOT_FORMAT
+	var graphOpts graph.Options
+
+	cmd := &cobra.Command{
+		Use:   "graph",
+		Short: "Outputs the graph of components, local modules and var files",
+		Long: `Outputs the dependency graph of the discovered components, the local modules
+		they call and the var files of each environment, as DOT or Mermaid.
+
+		For example, to render it with Graphviz:
+
+		tfvars-atlantis-config graph | dot -Tsvg > graph.svg`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			roots, err := flags.toRootOptions(logger.FromContext(cmd.Context()))
+			if err != nil {
+				return err
+			}
+			fsys, _, err := flags.repo()
+			if err != nil {
+				return err
+			}
+
+			components, err := discovery.DiscoverRoots(cmd.Context(), fsys, roots)
+			if err != nil {
+				return err
+			}
+
+			g, err := graph.New(components, graphOpts)
+			if err != nil {
+				return err
+			}
+
+			switch format {
+			case DOT_FORMAT:
+				return g.WriteDOT(cmd.OutOrStdout())
+			case MERMAID_FORMAT:
+				return g.WriteMermaid(cmd.OutOrStdout())
+			default:
+				return fmt.Errorf("unknown format %q, must be one of: %s, %s", format, DOT_FORMAT, MERMAID_FORMAT)
+			}
+		},
+	}
+
+	flags.AddDiscoveryFlags(cmd)
+	cmd.Flags().StringVar(&format, "format", format, "Output format: dot or mermaid")
+	cmd.Flags().StringVar(&graphOpts.Component, "component", graphOpts.Component, "Only graph the component with this path, and the modules it calls. Default is all components")
+	cmd.Flags().StringVar(&graphOpts.Environment, "environment", graphOpts.Environment, "Only graph the var files of this environment, and the components and modules using them. Default is all environments")
+
+	return cmd, nil
+}
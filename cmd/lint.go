@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+
+	"github.com/3bbbeau/tfvars-atlantis-config/discovery"
+	"github.com/3bbbeau/tfvars-atlantis-config/lint"
+	"github.com/3bbbeau/tfvars-atlantis-config/logger"
+	"github.com/3bbbeau/tfvars-atlantis-config/repocfg"
+	"github.com/spf13/cobra"
+)
+
+const (
+	TEXT_FORMAT = "text"
+	JSON_FORMAT = "json"
+)
+
+// NewLintCmd creates a new `lint` command, while applying the discovery flags
+// with their defaults overlayed by the flags passed in by the caller.
+func NewLintCmd() (*cobra.Command, error) {
+	return newFindingsCmd(&cobra.Command{
+		Use:   "lint",
+		Short: "Validates tfvars files against the variables of their component",
+		Long: `Validates the var files of each discovered component against the variable
+		blocks declared by the component, reporting undeclared variables, required
+		variables missing from an environment and values of the wrong primitive type.
+
+		Exits with an error if any problems are found.`,
+	}, lint.Lint, "found %d problem(s) in var files")
+}
+
+// NewScanSecretsCmd creates a new `scan-secrets` command, while applying the
+// discovery flags with their defaults overlayed by the flags passed in by the
+// caller.
+func NewScanSecretsCmd() (*cobra.Command, error) {
+	return newFindingsCmd(&cobra.Command{
+		Use:   "scan-secrets",
+		Short: "Detects secrets committed in tfvars files",
+		Long: `Scans the var files of each discovered component for values of variables
+		declared sensitive, strings matching well-known credential formats and
+		random looking strings.
+
+		Exits with an error if any secrets are found, so it can fail a pre-workflow hook.`,
+	}, lint.ScanSecrets, "found %d secret(s) in var files, remove them and pass them via TF_VAR_ environment variables or a secret store")
+}
+
+// newFindingsCmd completes a command which discovers components, reports the
+// findings of check on them, and fails with the message if there are any.
+func newFindingsCmd(cmd *cobra.Command, check func(fs.FS, []repocfg.Component) ([]lint.Finding, error), failure string) (*cobra.Command, error) {
+	flags, err := NewFlags()
+	if err != nil {
+		return nil, fmt.Errorf("new flags: %w", err)
+	}
+	format := TEXT_FORMAT
+
+	cmd.SilenceUsage = true
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		roots, err := flags.toRootOptions(logger.FromContext(cmd.Context()))
+		if err != nil {
+			return err
+		}
+
+		fsys, _, err := flags.repo()
+		if err != nil {
+			return err
+		}
+		components, err := discovery.DiscoverRoots(cmd.Context(), fsys, roots)
+		if err != nil {
+			return err
+		}
+
+		findings, err := check(fsys, components)
+		if err != nil {
+			return err
+		}
+
+		err = writeFindings(cmd.OutOrStdout(), format, findings)
+		if err != nil {
+			return err
+		}
+
+		return findingsError(failure, findings)
+	}
+
+	flags.AddDiscoveryFlags(cmd)
+	cmd.Flags().StringVar(&format, "format", format, "Output format of the findings: text or json")
+
+	return cmd, nil
+}
+
+// findingsError returns an error with the failure message and the number of
+// findings, or nil if there are none. Files that cannot be parsed are counted
+// on their own, so they are not mistaken for the findings of the check, e.g.
+// secrets.
+func findingsError(failure string, findings []lint.Finding) error {
+	parse := 0
+	for _, f := range findings {
+		if f.Rule == lint.PARSE_RULE {
+			parse++
+		}
+	}
+
+	switch n := len(findings) - parse; {
+	case n > 0 && parse > 0:
+		return fmt.Errorf(failure+", and could not parse %d file(s)", n, parse)
+	case n > 0:
+		return fmt.Errorf(failure, n)
+	case parse > 0:
+		return fmt.Errorf("could not parse %d file(s)", parse)
+	}
+	return nil
+}
+
+// writeFindings writes the findings in the given format, one per line for
+// text, or as a JSON array.
+func writeFindings(w io.Writer, format string, findings []lint.Finding) error {
+	switch format {
+	case TEXT_FORMAT:
+		for _, f := range findings {
+			if _, err := fmt.Fprintln(w, f); err != nil {
+				return err
+			}
+		}
+		return nil
+	case JSON_FORMAT:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(findings)
+	default:
+		return fmt.Errorf("unknown format %q, must be one of: %s, %s", format, TEXT_FORMAT, JSON_FORMAT)
+	}
+}
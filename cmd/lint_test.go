@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/3bbbeau/tfvars-atlantis-config/lint"
+)
+
+// Tests the findingsError function counts files that cannot be parsed apart
+// from the findings of the check.
+func Test_FindingsError(t *testing.T) {
+	t.Parallel()
+
+	secret := lint.Finding{Rule: lint.PATTERN_RULE}
+	parse := lint.Finding{Rule: lint.PARSE_RULE}
+
+	tests := []struct {
+		name     string
+		findings []lint.Finding
+		want     string
+	}{
+		{
+			name: "None",
+		},
+		{
+			name:     "Secrets",
+			findings: []lint.Finding{secret, secret},
+			want:     "found 2 secret(s)",
+		},
+		{
+			name:     "ParseErrors",
+			findings: []lint.Finding{parse},
+			want:     "could not parse 1 file(s)",
+		},
+		{
+			name:     "SecretsAndParseErrors",
+			findings: []lint.Finding{secret, parse, parse},
+			want:     "found 1 secret(s), and could not parse 2 file(s)",
+		},
+	}
+
+	for _, tc := range tests {
+		err := findingsError("found %d secret(s)", tc.findings)
+		got := ""
+		if err != nil {
+			got = err.Error()
+		}
+		if got != tc.want {
+			t.Errorf("%s: findingsError() = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/3bbbeau/tfvars-atlantis-config/discovery"
+	"github.com/3bbbeau/tfvars-atlantis-config/logger"
+	"github.com/3bbbeau/tfvars-atlantis-config/repocfg"
+	"github.com/runatlantis/atlantis/server/core/config/raw"
+	"github.com/spf13/cobra"
+)
+
+const CSV_FORMAT = "csv"
+
+// listColumns are the headers of the table and CSV output of `list`
+var listColumns = []string{"COMPONENT", "ENVIRONMENT", "VAR FILES", "WORKSPACE", "PROJECT", "TERRAFORM VERSION", "VERSION SOURCE"}
+
+// Entry describes a project that `generate` would make, and the component
+// and var files it is generated from.
+type Entry struct {
+	Component        string   `json:"component"`
+	Environment      string   `json:"environment"`
+	VarFiles         []string `json:"var_files"`
+	Workspace        string   `json:"workspace"`
+	Project          string   `json:"project"`
+	TerraformVersion string   `json:"terraform_version"`
+	// VersionSource is the version file the Terraform version was read
+	// from, or --terraform-version.
+	VersionSource string `json:"version_source"`
+}
+
+// row returns the fields of the entry in the order of listColumns
+func (e Entry) row() []string {
+	return []string{e.Component, e.Environment, strings.Join(e.VarFiles, ","), e.Workspace, e.Project, e.TerraformVersion, e.VersionSource}
+}
+
+// NewListCmd creates a new `list` command, while applying the discovery and
+// generate flags with their defaults overlayed by the flags passed in by the
+// caller.
+func NewListCmd() (*cobra.Command, error) {
+	flags, err := NewFlags()
+	if err != nil {
+		return nil, fmt.Errorf("new flags: %w", err)
+	}
+	format := TEXT_FORMAT
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "Lists the projects generate would make",
+		Long: `Lists each project generate would make, with its component, environment,
+		var files, workspace and Terraform version, as an aligned table, or as JSON or
+		CSV for other tools to consume.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			roots, err := flags.toRootOptions(logger.FromContext(cmd.Context()))
+			if err != nil {
+				return err
+			}
+			fsys, _, err := flags.repo()
+			if err != nil {
+				return err
+			}
+
+			// The projects are listed from the config generate would write,
+			// with the options of the root each was generated from.
+			result, err := discovery.Run(cmd.Context(), fsys, roots)
+			if err != nil {
+				return err
+			}
+
+			return writeEntries(cmd.OutOrStdout(), format, listEntries(result.Config))
+		},
+	}
+
+	flags.AddDiscoveryFlags(cmd)
+	flags.AddRepoCfgFlags(cmd)
+	cmd.Flags().StringVar(&format, "format", format, "Output format: text, json or csv")
+
+	return cmd, nil
+}
+
+// listEntries returns an entry for each project of the generated config, in
+// order.
+func listEntries(cfg *repocfg.ExtRawRepoCfg) []Entry {
+	entries := []Entry{}
+	for _, p := range cfg.Projects {
+		e := Entry{
+			Component:     p.Component,
+			Environment:   p.Environment,
+			VarFiles:      p.VarFiles,
+			Workspace:     raw.DefaultWorkspace,
+			Project:       *p.Name,
+			VersionSource: p.TerraformVersionSource,
+		}
+		if p.Workspace != nil {
+			e.Workspace = *p.Workspace
+		}
+		if p.TerraformVersion != nil {
+			e.TerraformVersion = *p.TerraformVersion
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+// writeEntries writes the entries as an aligned table, JSON or CSV
+func writeEntries(w io.Writer, format string, entries []Entry) error {
+	switch format {
+	case TEXT_FORMAT:
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, strings.Join(listColumns, "\t"))
+		for _, e := range entries {
+			row := e.row()
+			for i, field := range row {
+				if field == "" {
+					row[i] = "-"
+				}
+			}
+			fmt.Fprintln(tw, strings.Join(row, "\t"))
+		}
+		return tw.Flush()
+	case JSON_FORMAT:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(entries)
+	case CSV_FORMAT:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(listColumns); err != nil {
+			return err
+		}
+		for _, e := range entries {
+			if err := cw.Write(e.row()); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	default:
+		return fmt.Errorf("unknown format %q, must be one of: %s, %s, %s", format, TEXT_FORMAT, JSON_FORMAT, CSV_FORMAT)
+	}
+}
@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/3bbbeau/tfvars-atlantis-config/repocfg"
+	"github.com/google/go-cmp/cmp"
+)
+
+// Tests the listEntries function describes each project of the generated
+// config with the var files and the source of the Terraform version it was
+// generated from.
+func Test_ListEntries(t *testing.T) {
+	t.Parallel()
+
+	components := []repocfg.Component{
+		{
+			Path: "db",
+			VarFiles: []repocfg.VarFile{
+				{Path: "db/dev.tfvars", Environment: "dev"},
+				{Path: "db/envs/prd/terraform.tfvars", Environment: "prd"},
+			},
+		},
+		{
+			Path: "modules/vpc",
+		},
+		{
+			Path:                 "network",
+			VarFiles:             []repocfg.VarFile{{Path: "network/dev.tfvars", Environment: "dev"}},
+			TerraformVersion:     "1.9.0",
+			TerraformVersionFile: ".terraform-version",
+		},
+	}
+
+	tests := []struct {
+		name string
+		opts repocfg.Options
+		want []Entry
+	}{
+		{
+			name: "Flag",
+			opts: repocfg.Options{
+				DefaultTerraformVersion:       "1.6.0",
+				DefaultTerraformVersionSource: "--terraform-version",
+				IncludeNoVarFiles:             true,
+				UseWorkspaces:                 true,
+			},
+			want: []Entry{
+				{Component: "db", Environment: "dev", VarFiles: []string{"db/dev.tfvars"}, Workspace: "dev", Project: "db-dev", TerraformVersion: "1.6.0", VersionSource: "--terraform-version"},
+				{Component: "db", Environment: "prd", VarFiles: []string{"db/envs/prd/terraform.tfvars"}, Workspace: "prd", Project: "db-prd", TerraformVersion: "1.6.0", VersionSource: "--terraform-version"},
+				{Component: "modules/vpc", Workspace: "default", Project: "modules-vpc", TerraformVersion: "1.6.0", VersionSource: "--terraform-version"},
+				{Component: "network", Environment: "dev", VarFiles: []string{"network/dev.tfvars"}, Workspace: "dev", Project: "network-dev", TerraformVersion: "1.9.0", VersionSource: ".terraform-version"},
+			},
+		},
+		{
+			name: "Root",
+			opts: repocfg.Options{
+				DefaultTerraformVersion:       "1.7.0",
+				DefaultTerraformVersionSource: "terraform_version of root infra",
+			},
+			want: []Entry{
+				{Component: "db", Environment: "dev", VarFiles: []string{"db/dev.tfvars"}, Workspace: "default", Project: "db-dev", TerraformVersion: "1.7.0", VersionSource: "terraform_version of root infra"},
+				{Component: "db", Environment: "prd", VarFiles: []string{"db/envs/prd/terraform.tfvars"}, Workspace: "default", Project: "db-prd", TerraformVersion: "1.7.0", VersionSource: "terraform_version of root infra"},
+				{Component: "network", Environment: "dev", VarFiles: []string{"network/dev.tfvars"}, Workspace: "default", Project: "network-dev", TerraformVersion: "1.9.0", VersionSource: ".terraform-version"},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		cfg, err := repocfg.NewRepoCfg(components, tc.opts)
+		if err != nil {
+			t.Errorf("%s: NewRepoCfg() error: %s", tc.name, err)
+			continue
+		}
+
+		got := listEntries(cfg)
+		if !cmp.Equal(got, tc.want) {
+			t.Errorf(`%s: listEntries()
+			diff %s`, tc.name, cmp.Diff(got, tc.want))
+		}
+	}
+}
+
+// Tests the writeEntries function for each output format
+func Test_WriteEntries(t *testing.T) {
+	t.Parallel()
+
+	entries := []Entry{
+		{Component: "db", Environment: "dev", VarFiles: []string{"db/envs/dev/terraform.tfvars", "db/envs/dev/extra.tfvars"}, Workspace: "default", Project: "db-dev"},
+	}
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{
+			format: TEXT_FORMAT,
+			want: `COMPONENT  ENVIRONMENT  VAR FILES                                              WORKSPACE  PROJECT  TERRAFORM VERSION  VERSION SOURCE
+db         dev          db/envs/dev/terraform.tfvars,db/envs/dev/extra.tfvars  default    db-dev   -                  -
+`,
+		},
+		{
+			format: CSV_FORMAT,
+			want: `COMPONENT,ENVIRONMENT,VAR FILES,WORKSPACE,PROJECT,TERRAFORM VERSION,VERSION SOURCE
+db,dev,"db/envs/dev/terraform.tfvars,db/envs/dev/extra.tfvars",default,db-dev,,
+`,
+		},
+		{
+			format: JSON_FORMAT,
+			want: `[
+  {
+    "component": "db",
+    "environment": "dev",
+    "var_files": [
+      "db/envs/dev/terraform.tfvars",
+      "db/envs/dev/extra.tfvars"
+    ],
+    "workspace": "default",
+    "project": "db-dev",
+    "terraform_version": "",
+    "version_source": ""
+  }
+]
+`,
+		},
+	}
+
+	for _, tc := range tests {
+		got := new(bytes.Buffer)
+		if err := writeEntries(got, tc.format, entries); err != nil {
+			t.Errorf("writeEntries(%s) error: %s", tc.format, err)
+		}
+
+		if got.String() != tc.want {
+			t.Errorf(`writeEntries(%s)
+			diff %s`, tc.format, cmp.Diff(got.String(), tc.want))
+		}
+	}
+
+	if err := writeEntries(new(bytes.Buffer), "yaml", entries); err == nil {
+		t.Errorf("writeEntries() expected error for unknown format")
+	}
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// writeFileAtomic writes data to the named file through a temporary file in
+// the same directory, renamed over it once written, so that the file is never
+// left partially written.
+//
+// The file is not rewritten if it already has the same content, keeping its
+// modification time. An existing file keeps its mode, and a new file is
+// created with the given one. The parent directories are created if
+// createDirs is set.
+func writeFileAtomic(name string, data []byte, perm fs.FileMode, createDirs bool) (bool, error) {
+	existing, err := os.ReadFile(name)
+	switch {
+	case err == nil && bytes.Equal(existing, data):
+		return false, nil
+	case err != nil && !errors.Is(err, fs.ErrNotExist):
+		return false, err
+	}
+
+	if info, err := os.Stat(name); err == nil {
+		perm = info.Mode().Perm()
+	}
+
+	dir := filepath.Dir(name)
+	if createDirs {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return false, fmt.Errorf("creating directory for %s: %w", name, err)
+		}
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(name)+".*.tmp")
+	if err != nil {
+		return false, fmt.Errorf("creating temporary file for %s: %w", name, err)
+	}
+	// Removing the temporary file fails once it has been renamed, which is
+	// expected.
+	defer os.Remove(tmp.Name()) // nolint:errcheck
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return false, fmt.Errorf("writing %s: %w", tmp.Name(), err)
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return false, fmt.Errorf("setting mode of %s: %w", tmp.Name(), err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return false, fmt.Errorf("syncing %s: %w", tmp.Name(), err)
+	}
+	if err := tmp.Close(); err != nil {
+		return false, fmt.Errorf("closing %s: %w", tmp.Name(), err)
+	}
+
+	if err := os.Rename(tmp.Name(), name); err != nil {
+		return false, fmt.Errorf("replacing %s: %w", name, err)
+	}
+	return true, nil
+}
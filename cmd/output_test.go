@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Tests writeFileAtomic replaces the file keeping its mode, leaves identical
+// files untouched, and only creates parent directories when asked to.
+func Test_WriteFileAtomic(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	name := filepath.Join(dir, "atlantis.yaml")
+
+	written, err := writeFileAtomic(name, []byte("version: 3\n"), 0o644, false)
+	if err != nil || !written {
+		t.Fatalf("writeFileAtomic() new file = %v, %v", written, err)
+	}
+
+	if err := os.Chmod(name, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(name, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	written, err = writeFileAtomic(name, []byte("version: 3\n"), 0o644, false)
+	if err != nil || written {
+		t.Errorf("writeFileAtomic() identical file = %v, %v, want not written", written, err)
+	}
+	if info, _ := os.Stat(name); !info.ModTime().Equal(old) {
+		t.Errorf("writeFileAtomic() identical file modified at %s, want %s", info.ModTime(), old)
+	}
+
+	written, err = writeFileAtomic(name, []byte("version: 3\nprojects: []\n"), 0o644, false)
+	if err != nil || !written {
+		t.Fatalf("writeFileAtomic() changed file = %v, %v", written, err)
+	}
+	if got, _ := os.ReadFile(name); string(got) != "version: 3\nprojects: []\n" {
+		t.Errorf("writeFileAtomic() content = %q", got)
+	}
+	if info, _ := os.Stat(name); info.Mode().Perm() != 0o600 {
+		t.Errorf("writeFileAtomic() mode = %s, want %s", info.Mode().Perm(), os.FileMode(0o600))
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("writeFileAtomic() left %d files, want only %s", len(entries), name)
+	}
+
+	nested := filepath.Join(dir, "config", "atlantis", "atlantis.yaml")
+	if _, err := writeFileAtomic(nested, []byte("version: 3\n"), 0o644, false); err == nil {
+		t.Errorf("writeFileAtomic() expected error for missing parent directory")
+	}
+	if _, err := writeFileAtomic(nested, []byte("version: 3\n"), 0o644, true); err != nil {
+		t.Errorf("writeFileAtomic() creating parent directories error: %s", err)
+	}
+}
@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// findRepoRoot returns the nearest directory at or above dir containing a
+// `.git` directory, or file for worktrees and submodules, and the path of dir
+// relative to it. It returns false if dir is not within a git repo.
+func findRepoRoot(dir string) (string, string, bool, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", "", false, err
+	}
+
+	for root := abs; ; {
+		_, err := os.Stat(filepath.Join(root, ".git"))
+		switch {
+		case err == nil:
+			rel, err := filepath.Rel(root, abs)
+			if err != nil {
+				return "", "", false, err
+			}
+			return root, filepath.ToSlash(rel), true, nil
+		case !errors.Is(err, fs.ErrNotExist):
+			return "", "", false, err
+		}
+
+		parent := filepath.Dir(root)
+		if parent == root {
+			return "", "", false, nil
+		}
+		root = parent
+	}
+}
+
+// prefixFS presents a file system under a directory prefix, so that the paths
+// of the files within it are prefixed, e.g. for a checkout of part of a repo.
+// Paths outside of the prefix do not exist.
+type prefixFS struct {
+	fsys   fs.FS
+	prefix string
+}
+
+// rel returns the path of name within the prefixed file system.
+func (p prefixFS) rel(op, name string) (string, error) {
+	switch {
+	case name == p.prefix:
+		return ".", nil
+	case strings.HasPrefix(name, p.prefix+"/"):
+		return strings.TrimPrefix(name, p.prefix+"/"), nil
+	}
+	return "", &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+}
+
+// Open implements fs.FS.
+func (p prefixFS) Open(name string) (fs.File, error) {
+	rel, err := p.rel("open", name)
+	if err != nil {
+		return nil, err
+	}
+	return p.fsys.Open(rel)
+}
+
+// ReadDir implements fs.ReadDirFS.
+func (p prefixFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	rel, err := p.rel("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	return fs.ReadDir(p.fsys, rel)
+}
+
+// ReadFile implements fs.ReadFileFS.
+func (p prefixFS) ReadFile(name string) ([]byte, error) {
+	rel, err := p.rel("readfile", name)
+	if err != nil {
+		return nil, err
+	}
+	return fs.ReadFile(p.fsys, rel)
+}
+
+// Stat implements fs.StatFS.
+func (p prefixFS) Stat(name string) (fs.FileInfo, error) {
+	rel, err := p.rel("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	return fs.Stat(p.fsys, rel)
+}
+
+// repo returns the file system of the repo containing `--root`, and the path
+// of `--root` within it, so that paths are relative to the repo root as
+// Atlantis expects. The repo root is `--root` itself with `--path-prefix` as
+// its path, or otherwise the enclosing git repo. If there is none, `--root` is
+// assumed to be the repo root.
+func (flags *Flags) repo() (fs.FS, string, error) {
+	if flags.PathPrefix != "" {
+		prefix := path.Clean(filepath.ToSlash(flags.PathPrefix))
+		if !fs.ValidPath(prefix) {
+			return nil, "", fmt.Errorf("path prefix %q must be a relative path within the repo", flags.PathPrefix)
+		}
+		if prefix == "." {
+			return os.DirFS(flags.Root), ".", nil
+		}
+		return prefixFS{fsys: os.DirFS(flags.Root), prefix: prefix}, prefix, nil
+	}
+
+	root, rel, ok, err := findRepoRoot(flags.Root)
+	if err != nil {
+		return nil, "", fmt.Errorf("finding git repo root: %w", err)
+	}
+	if !ok {
+		return os.DirFS(flags.Root), ".", nil
+	}
+	return os.DirFS(root), rel, nil
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// Tests the findRepoRoot function finds the nearest directory with a .git
+// directory or file, and the path relative to it.
+func Test_FindRepoRoot(t *testing.T) {
+	t.Parallel()
+
+	tmp := t.TempDir()
+	for _, dir := range []string{"repo/.git", "repo/infra/aws", "repo/submodule/infra", "norepo"} {
+		if err := os.MkdirAll(filepath.Join(tmp, dir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(tmp, "repo/submodule/.git"), []byte("gitdir: ../.git/modules/submodule"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		dir      string
+		wantRoot string
+		wantRel  string
+		wantOk   bool
+	}{
+		{name: "RepoRoot", dir: "repo", wantRoot: "repo", wantRel: ".", wantOk: true},
+		{name: "Nested", dir: "repo/infra/aws", wantRoot: "repo", wantRel: "infra/aws", wantOk: true},
+		{name: "Submodule", dir: "repo/submodule/infra", wantRoot: "repo/submodule", wantRel: "infra", wantOk: true},
+		{name: "NoRepo", dir: "norepo"},
+	}
+
+	for _, tc := range tests {
+		root, rel, ok, err := findRepoRoot(filepath.Join(tmp, tc.dir))
+		if err != nil {
+			t.Errorf("%s: findRepoRoot() error: %s", tc.name, err)
+			continue
+		}
+		// The temporary directory may itself be within a git repo
+		if !tc.wantOk {
+			if ok && strings.HasPrefix(root, tmp) {
+				t.Errorf("%s: findRepoRoot() found %s", tc.name, root)
+			}
+			continue
+		}
+
+		got := []any{root, rel, ok}
+		want := []any{filepath.Join(tmp, tc.wantRoot), tc.wantRel, tc.wantOk}
+		if !cmp.Equal(got, want) {
+			t.Errorf(`%s: findRepoRoot()
+			diff %s`, tc.name, cmp.Diff(got, want))
+		}
+	}
+}
+
+// Tests the prefixFS file system presents its files under the prefix only.
+func Test_PrefixFS(t *testing.T) {
+	t.Parallel()
+
+	fsys := prefixFS{
+		fsys: fstest.MapFS{
+			"aws/main.tf":    {Data: []byte("# main")},
+			"aws/dev.tfvars": {},
+		},
+		prefix: "infra",
+	}
+
+	var got []string
+	err := fs.WalkDir(fsys, "infra", func(p string, d fs.DirEntry, err error) error {
+		got = append(got, p)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("WalkDir() error: %s", err)
+	}
+	want := []string{"infra", "infra/aws", "infra/aws/dev.tfvars", "infra/aws/main.tf"}
+	if !cmp.Equal(got, want) {
+		t.Errorf(`WalkDir()
+		diff %s`, cmp.Diff(got, want))
+	}
+
+	b, err := fs.ReadFile(fsys, "infra/aws/main.tf")
+	if err != nil || string(b) != "# main" {
+		t.Errorf("ReadFile() = %q, %v", b, err)
+	}
+
+	for _, name := range []string{".", "aws/main.tf", "infrastructure/aws/main.tf"} {
+		if _, err := fs.Stat(fsys, name); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("Stat(%s) expected not to exist, got %v", name, err)
+		}
+	}
+}
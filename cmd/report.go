@@ -0,0 +1,248 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/3bbbeau/tfvars-atlantis-config/discovery"
+	"github.com/runatlantis/atlantis/server/core/config/raw"
+	"go.uber.org/zap/zapcore"
+)
+
+// Report describes a `generate` run for other tools, e.g. CI annotating pull
+// requests, written as JSON to `--report`.
+type Report struct {
+	Summary    Summary           `json:"summary"`
+	Components []ReportComponent `json:"components"`
+	Projects   []ReportProject   `json:"projects"`
+	Ignored    []IgnoredFile     `json:"ignored"`
+	Warnings   []Warning         `json:"warnings"`
+	Timings    Timings           `json:"timings"`
+}
+
+// Summary counts what was discovered and generated in a run. Components
+// only counts those projects are generated from, and Modules the components
+// called by another component as a local module.
+type Summary struct {
+	Components int `json:"components"`
+	Modules    int `json:"modules"`
+	Projects   int `json:"projects"`
+	Ignored    int `json:"ignored"`
+	Warnings   int `json:"warnings"`
+}
+
+// ReportComponent describes a discovered component.
+type ReportComponent struct {
+	Path                 string          `json:"path"`
+	Module               bool            `json:"module"`
+	VarFiles             []ReportVarFile `json:"var_files"`
+	Modules              []string        `json:"modules"`
+	TerraformVersion     string          `json:"terraform_version,omitempty"`
+	TerraformVersionFile string          `json:"terraform_version_file,omitempty"`
+}
+
+// ReportVarFile describes a var file claimed by a component.
+type ReportVarFile struct {
+	Path        string `json:"path"`
+	Environment string `json:"environment"`
+	Skipped     bool   `json:"skipped"`
+}
+
+// ReportProject describes a generated project.
+type ReportProject struct {
+	Name             string `json:"name"`
+	Dir              string `json:"dir"`
+	Workspace        string `json:"workspace"`
+	TerraformVersion string `json:"terraform_version,omitempty"`
+}
+
+// IgnoredFile is a var file no project was generated from, and the reason.
+type IgnoredFile struct {
+	File   string `json:"file"`
+	Reason string `json:"reason"`
+}
+
+// Warning is a warning logged during the run, with its fields, e.g. the
+// component and var files of an environment collision.
+type Warning struct {
+	Message string         `json:"message"`
+	Fields  map[string]any `json:"fields"`
+}
+
+// Timings are the durations of the steps of the run, in seconds.
+type Timings struct {
+	Generate float64 `json:"generate_seconds"`
+	Write    float64 `json:"write_seconds"`
+	Total    float64 `json:"total_seconds"`
+}
+
+// warningRecorder is a zapcore.Core recording the warnings and errors logged
+// during a run, with the fields of the logger and the entry, for the report.
+type warningRecorder struct {
+	zapcore.LevelEnabler
+	fields   []zapcore.Field
+	mu       *sync.Mutex
+	warnings *[]Warning
+}
+
+// newWarningRecorder creates a warningRecorder with no warnings recorded.
+func newWarningRecorder() *warningRecorder {
+	return &warningRecorder{
+		LevelEnabler: zapcore.WarnLevel,
+		mu:           &sync.Mutex{},
+		warnings:     &[]Warning{},
+	}
+}
+
+// With returns a recorder adding the fields to the warnings it records,
+// sharing the warnings recorded.
+func (r *warningRecorder) With(fields []zapcore.Field) zapcore.Core {
+	clone := *r
+	clone.fields = append(append([]zapcore.Field{}, r.fields...), fields...)
+	return &clone
+}
+
+// Check adds the recorder to the checked entry if its level is enabled.
+func (r *warningRecorder) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if r.Enabled(ent.Level) {
+		return ce.AddCore(ent, r)
+	}
+	return ce
+}
+
+// Write records the entry as a warning.
+func (r *warningRecorder) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range r.fields {
+		f.AddTo(enc)
+	}
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	*r.warnings = append(*r.warnings, Warning{Message: ent.Message, Fields: enc.Fields})
+	return nil
+}
+
+// Sync does nothing, as warnings are recorded in memory.
+func (r *warningRecorder) Sync() error {
+	return nil
+}
+
+// All returns the warnings recorded, in the order they were logged.
+func (r *warningRecorder) All() []Warning {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Warning{}, *r.warnings...)
+}
+
+// newReport describes the result of a run and the warnings logged during it.
+func newReport(result *discovery.Result, warnings []Warning) Report {
+	r := Report{
+		Components: []ReportComponent{},
+		Projects:   []ReportProject{},
+		Ignored:    []IgnoredFile{},
+		Warnings:   []Warning{},
+	}
+
+	for _, c := range result.Components {
+		rc := ReportComponent{
+			Path:                 c.Path,
+			Module:               c.IsModule,
+			VarFiles:             []ReportVarFile{},
+			Modules:              c.Modules,
+			TerraformVersion:     c.TerraformVersion,
+			TerraformVersionFile: c.TerraformVersionFile,
+		}
+		if rc.Modules == nil {
+			rc.Modules = []string{}
+		}
+		if c.IsModule {
+			r.Summary.Modules++
+		}
+		for _, v := range c.VarFiles {
+			rc.VarFiles = append(rc.VarFiles, ReportVarFile{
+				Path:        v.Path,
+				Environment: v.Environment,
+				Skipped:     v.Annotations.Skip,
+			})
+			if v.Annotations.Skip {
+				r.Ignored = append(r.Ignored, IgnoredFile{
+					File:   v.Path,
+					Reason: fmt.Sprintf("annotated with %sskip", discovery.ANNOTATION_PREFIX),
+				})
+			}
+		}
+		r.Components = append(r.Components, rc)
+	}
+
+	for _, c := range result.Ignored {
+		r.Ignored = append(r.Ignored, IgnoredFile{File: c.VarFile, Reason: c.Reason})
+	}
+
+	for _, p := range result.Config.Projects {
+		rp := ReportProject{
+			Name:      *p.Name,
+			Dir:       *p.Dir,
+			Workspace: raw.DefaultWorkspace,
+		}
+		if p.Workspace != nil {
+			rp.Workspace = *p.Workspace
+		}
+		if p.TerraformVersion != nil {
+			rp.TerraformVersion = *p.TerraformVersion
+		}
+		r.Projects = append(r.Projects, rp)
+	}
+
+	r.Warnings = append(r.Warnings, warnings...)
+
+	dirs := map[string]bool{}
+	for _, p := range r.Projects {
+		dirs[p.Dir] = true
+	}
+	r.Summary.Components = len(dirs)
+	r.Summary.Projects = len(r.Projects)
+	r.Summary.Ignored = len(r.Ignored)
+	r.Summary.Warnings = len(r.Warnings)
+	return r
+}
+
+// seconds returns the duration in seconds, rounded to milliseconds.
+func seconds(d time.Duration) float64 {
+	return d.Round(time.Millisecond).Seconds()
+}
+
+// writeSummary writes a line summarizing the report, e.g.:
+//
+//	generated 3 projects from 2 components in 0.12s, ignored 1 var file, 1 warning
+func writeSummary(w io.Writer, r Report) error {
+	_, err := fmt.Fprintf(w, "generated %s from %s in %.2fs, ignored %s, %s\n",
+		plural(r.Summary.Projects, "project"),
+		plural(r.Summary.Components, "component"),
+		r.Timings.Total,
+		plural(r.Summary.Ignored, "var file"),
+		plural(r.Summary.Warnings, "warning"),
+	)
+	return err
+}
+
+// plural returns the count followed by the noun, pluralized unless 1.
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+// writeReport writes the report as indented JSON.
+func writeReport(w io.Writer, r Report) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
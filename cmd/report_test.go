@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/3bbbeau/tfvars-atlantis-config/discovery"
+	"github.com/3bbbeau/tfvars-atlantis-config/repocfg"
+	"github.com/google/go-cmp/cmp"
+	"go.uber.org/zap"
+)
+
+// Tests the newReport function describes the components, projects, ignored
+// var files and warnings of a run.
+func Test_NewReport(t *testing.T) {
+	t.Parallel()
+
+	components := []repocfg.Component{
+		{
+			Path: "db",
+			VarFiles: []repocfg.VarFile{
+				{Path: "db/dev.tfvars", Environment: "dev", Annotations: repocfg.Annotations{Skip: true}},
+				{Path: "db/prd.tfvars", Environment: "prd"},
+			},
+			Modules:              []string{"modules/rds"},
+			TerraformVersion:     "1.9.0",
+			TerraformVersionFile: "db/.terraform-version",
+		},
+		{
+			Path:     "modules/rds",
+			IsModule: true,
+		},
+	}
+	cfg, err := repocfg.NewRepoCfg(components, repocfg.Options{UseWorkspaces: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := &discovery.Result{
+		Config:     cfg,
+		Components: components,
+		Ignored:    []discovery.Claim{{VarFile: "orphan/dev.tfvars", Reason: "no component in its directory or any parent directory"}},
+	}
+
+	recorder := newWarningRecorder()
+	logger := zap.New(recorder)
+	logger.Debug("ignored")
+	logger.With(zap.String("component", "db")).Warn("no backend config for the environment of var file", zap.String("var_file", "db/prd.tfvars"))
+
+	want := Report{
+		Summary: Summary{Components: 1, Modules: 1, Projects: 1, Ignored: 2, Warnings: 1},
+		Components: []ReportComponent{
+			{
+				Path: "db",
+				VarFiles: []ReportVarFile{
+					{Path: "db/dev.tfvars", Environment: "dev", Skipped: true},
+					{Path: "db/prd.tfvars", Environment: "prd"},
+				},
+				Modules:              []string{"modules/rds"},
+				TerraformVersion:     "1.9.0",
+				TerraformVersionFile: "db/.terraform-version",
+			},
+			{
+				Path:     "modules/rds",
+				Module:   true,
+				VarFiles: []ReportVarFile{},
+				Modules:  []string{},
+			},
+		},
+		Projects: []ReportProject{
+			{Name: "db-prd", Dir: "db", Workspace: "prd", TerraformVersion: "1.9.0"},
+		},
+		Ignored: []IgnoredFile{
+			{File: "db/dev.tfvars", Reason: "annotated with atlantis:skip"},
+			{File: "orphan/dev.tfvars", Reason: "no component in its directory or any parent directory"},
+		},
+		Warnings: []Warning{
+			{
+				Message: "no backend config for the environment of var file",
+				Fields:  map[string]any{"component": "db", "var_file": "db/prd.tfvars"},
+			},
+		},
+	}
+
+	got := newReport(result, recorder.All())
+	if !cmp.Equal(got, want) {
+		t.Errorf(`newReport()
+		diff %s`, cmp.Diff(got, want))
+	}
+}
+
+// Tests the writeSummary function counts the report in a single line.
+func Test_WriteSummary(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		report Report
+		want   string
+	}{
+		{
+			report: Report{},
+			want:   "generated 0 projects from 0 components in 0.00s, ignored 0 var files, 0 warnings\n",
+		},
+		{
+			report: Report{
+				Summary: Summary{Components: 1, Projects: 3, Ignored: 1, Warnings: 2},
+				Timings: Timings{Total: 0.123},
+			},
+			want: "generated 3 projects from 1 component in 0.12s, ignored 1 var file, 2 warnings\n",
+		},
+	}
+
+	for _, tc := range tests {
+		got := new(bytes.Buffer)
+		if err := writeSummary(got, tc.report); err != nil {
+			t.Errorf("writeSummary() error: %s", err)
+		}
+		if got.String() != tc.want {
+			t.Errorf(`writeSummary()
+			diff %s`, cmp.Diff(got.String(), tc.want))
+		}
+	}
+}
@@ -17,8 +17,8 @@ func New() (*cobra.Command, error) {
 		Short: "Generates Atlantis Config for Terraform projects",
 		Long: `tfvars-atlantis-config is a utility that generates Atlantis configurations for Terraform projects
 		that use tfvars files per environment.`,
-		PersistentPreRun: func(cmd *cobra.Command, args []string) {
-			setLogger(cmd)
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return setLogger(cmd)
 		},
 		Run: func(cmd *cobra.Command, args []string) {
 			err := cmd.Help()
@@ -28,6 +28,9 @@ func New() (*cobra.Command, error) {
 		},
 	}
 	cmd.PersistentFlags().Bool("debug", false, "Enable debug mode")
+	cmd.PersistentFlags().String("log-format", "", "Format of the logs: json or console. Default is json, or console with --debug")
+	cmd.PersistentFlags().String("log-level", "", "Minimum level of the logs: debug, info, warn or error. Default is info, or debug with --debug")
+	cmd.PersistentFlags().String("log-file", "", "Path of the file the logs are appended to. Default is stderr")
 
 	cmd.AddCommand(NewVersionCmd())
 	gCmd, err := NewGenerateCmd()
@@ -35,23 +38,61 @@ func New() (*cobra.Command, error) {
 		return nil, fmt.Errorf("creating generate command: %w", err)
 	}
 	cmd.AddCommand(gCmd)
+	dCmd, err := NewDiscoverCmd()
+	if err != nil {
+		return nil, fmt.Errorf("creating discover command: %w", err)
+	}
+	cmd.AddCommand(dCmd)
+	listCmd, err := NewListCmd()
+	if err != nil {
+		return nil, fmt.Errorf("creating list command: %w", err)
+	}
+	cmd.AddCommand(listCmd)
+	graphCmd, err := NewGraphCmd()
+	if err != nil {
+		return nil, fmt.Errorf("creating graph command: %w", err)
+	}
+	cmd.AddCommand(graphCmd)
+	lCmd, err := NewLintCmd()
+	if err != nil {
+		return nil, fmt.Errorf("creating lint command: %w", err)
+	}
+	cmd.AddCommand(lCmd)
+	sCmd, err := NewScanSecretsCmd()
+	if err != nil {
+		return nil, fmt.Errorf("creating scan-secrets command: %w", err)
+	}
+	cmd.AddCommand(sCmd)
 	cmd.AddCommand(NewMultiEnvCmd())
 
 	return cmd, nil
 }
 
-// setLogger sets the logger for the command's lifespan, based on the debug flag.
-func setLogger(cmd *cobra.Command) {
-	ctx := cmd.Context()
+// setLogger sets the logger for the command's lifespan, based on the debug
+// and log flags. Logs go to stderr, or --log-file, never to stdout, which is
+// left for the output of the command, e.g. the generated config.
+func setLogger(cmd *cobra.Command) error {
+	var opts logger.Options
+	var err error
+
+	if opts.Development, err = cmd.Flags().GetBool("debug"); err != nil {
+		return err
+	}
+	if opts.Format, err = cmd.Flags().GetString("log-format"); err != nil {
+		return err
+	}
+	if opts.Level, err = cmd.Flags().GetString("log-level"); err != nil {
+		return err
+	}
+	if opts.File, err = cmd.Flags().GetString("log-file"); err != nil {
+		return err
+	}
 
-	debug, err := cmd.Flags().GetBool("debug")
-	if err != nil || !debug {
-		z := zap.Must(zap.NewProductionConfig().Build())
-		ctx = logger.WithContext(ctx, z)
-	} else {
-		z := zap.Must(zap.NewDevelopmentConfig().Build())
-		ctx = logger.WithContext(ctx, z)
+	z, err := logger.New(opts)
+	if err != nil {
+		return err
 	}
 
-	cmd.SetContext(ctx)
+	cmd.SetContext(logger.WithContext(cmd.Context(), z))
+	return nil
 }
@@ -0,0 +1,154 @@
+package discovery
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"strconv"
+	"strings"
+
+	"github.com/3bbbeau/tfvars-atlantis-config/repocfg"
+	"go.uber.org/zap"
+)
+
+const (
+	// ANNOTATION_PREFIX starts each directive in a variable file, e.g.
+	// `# atlantis:autoplan=false`.
+	ANNOTATION_PREFIX = "atlantis:"
+
+	// ANNOTATION_JSON_KEY is the key of the directives in a JSON variable
+	// file, which Terraform ignores as a comment, e.g.
+	// `{"//": ["atlantis:skip"]}`.
+	ANNOTATION_JSON_KEY = "//"
+)
+
+// discoverAnnotations reads the directives of a variable file, from its
+// leading comments, or ANNOTATION_JSON_KEY if it is a JSON file. Unknown or
+// invalid directives are ignored with a warning.
+func discoverAnnotations(fsys fs.FS, logger *zap.Logger, varFile string) (repocfg.Annotations, error) {
+	var a repocfg.Annotations
+
+	b, err := fs.ReadFile(fsys, varFile)
+	if err != nil {
+		return a, err
+	}
+
+	var comments []string
+	if strings.HasSuffix(varFile, TFVARS_JSON_EXT) {
+		comments = jsonComments(b)
+	} else {
+		comments = leadingComments(b)
+	}
+
+	for _, comment := range comments {
+		comment = strings.TrimSpace(comment)
+		directive, ok := strings.CutPrefix(comment, ANNOTATION_PREFIX)
+		if !ok {
+			continue
+		}
+		if err := annotate(&a, directive); err != nil {
+			logger.Warn("ignoring annotation", zap.String("var_file", varFile), zap.String("annotation", comment), zap.Error(err))
+		}
+	}
+
+	return a, nil
+}
+
+// annotate sets the annotation of a directive, e.g. `autoplan=false`.
+func annotate(a *repocfg.Annotations, directive string) error {
+	key, value, hasValue := strings.Cut(directive, "=")
+	key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+
+	switch key {
+	case "skip":
+		if !hasValue {
+			a.Skip = true
+			return nil
+		}
+		skip, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("skip must be true or false")
+		}
+		a.Skip = skip
+	case "autoplan":
+		autoplan, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("autoplan must be true or false")
+		}
+		a.Autoplan = &autoplan
+	case "workspace":
+		if value == "" {
+			return fmt.Errorf("workspace must not be empty")
+		}
+		a.Workspace = value
+	case "apply_requirements":
+		a.ApplyRequirements = splitList(value)
+	case "plan_requirements":
+		a.PlanRequirements = splitList(value)
+	case "import_requirements":
+		a.ImportRequirements = splitList(value)
+	default:
+		return fmt.Errorf("unknown annotation %s", key)
+	}
+	return nil
+}
+
+// splitList splits a comma separated list, e.g. `approved,mergeable`. An
+// empty value is an empty list.
+func splitList(value string) []string {
+	list := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// leadingComments returns the text of the `#` and `//` line comments before
+// the first line of a variable file that is neither blank nor a comment.
+func leadingComments(b []byte) []string {
+	var comments []string
+
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, "#"):
+			comments = append(comments, strings.TrimPrefix(line, "#"))
+		case strings.HasPrefix(line, "//"):
+			comments = append(comments, strings.TrimPrefix(line, "//"))
+		default:
+			return comments
+		}
+	}
+	return comments
+}
+
+// jsonComments returns the comments under ANNOTATION_JSON_KEY of a JSON
+// variable file, either a string or a list of strings. Files that are not
+// valid JSON have none, and are reported by `lint`.
+func jsonComments(b []byte) []string {
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal(b, &values); err != nil {
+		return nil
+	}
+	raw, ok := values[ANNOTATION_JSON_KEY]
+	if !ok {
+		return nil
+	}
+
+	var comment string
+	if err := json.Unmarshal(raw, &comment); err == nil {
+		return []string{comment}
+	}
+	var comments []string
+	if err := json.Unmarshal(raw, &comments); err == nil {
+		return comments
+	}
+	return nil
+}
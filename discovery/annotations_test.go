@@ -0,0 +1,79 @@
+package discovery
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/3bbbeau/tfvars-atlantis-config/repocfg"
+	"github.com/google/go-cmp/cmp"
+	"go.uber.org/zap"
+)
+
+// Tests the discoverAnnotations function reads the directives of HCL and
+// JSON var files, ignoring unknown and invalid ones.
+func Test_DiscoverAnnotations(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		"skip.tfvars": {Data: []byte("# atlantis:skip\nregion = \"eu-west-1\"\n")},
+		"manual.tfvars": {Data: []byte(`# Production, applied by hand
+# atlantis:autoplan=false
+// atlantis:apply_requirements=approved, mergeable
+
+#atlantis:workspace=prod-eu
+region = "eu-west-1"
+# atlantis:skip
+`)},
+		"invalid.tfvars":      {Data: []byte("# atlantis:autoplan=maybe\n# atlantis:unknown\n# atlantis:plan_requirements=\n")},
+		"none.tfvars":         {Data: []byte("region = \"eu-west-1\"\n")},
+		"string.tfvars.json":  {Data: []byte(`{"//": "atlantis:skip", "region": "eu-west-1"}`)},
+		"list.tfvars.json":    {Data: []byte(`{"region": "eu-west-1", "//": ["Staging", "atlantis:workspace=stg", "atlantis:import_requirements=approved"]}`)},
+		"invalid.tfvars.json": {Data: []byte(`{"//": {"atlantis": "skip"}}`)},
+		"broken.tfvars.json":  {Data: []byte(`{"//": "atlantis:skip"`)},
+	}
+
+	tests := []struct {
+		varFile string
+		want    repocfg.Annotations
+	}{
+		{varFile: "skip.tfvars", want: repocfg.Annotations{Skip: true}},
+		{
+			varFile: "manual.tfvars",
+			want: repocfg.Annotations{
+				Autoplan:          ptr(false),
+				Workspace:         "prod-eu",
+				ApplyRequirements: []string{"approved", "mergeable"},
+			},
+		},
+		{varFile: "invalid.tfvars", want: repocfg.Annotations{PlanRequirements: []string{}}},
+		{varFile: "none.tfvars"},
+		{varFile: "string.tfvars.json", want: repocfg.Annotations{Skip: true}},
+		{
+			varFile: "list.tfvars.json",
+			want: repocfg.Annotations{
+				Workspace:          "stg",
+				ImportRequirements: []string{"approved"},
+			},
+		},
+		{varFile: "invalid.tfvars.json"},
+		{varFile: "broken.tfvars.json"},
+	}
+
+	for _, tc := range tests {
+		got, err := discoverAnnotations(fsys, zap.NewNop(), tc.varFile)
+		if err != nil {
+			t.Errorf("%s: discoverAnnotations() error: %s", tc.varFile, err)
+			continue
+		}
+
+		if !cmp.Equal(got, tc.want) {
+			t.Errorf(`%s: discoverAnnotations()
+			diff %s`, tc.varFile, cmp.Diff(got, tc.want))
+		}
+	}
+}
+
+// ptr returns a pointer to v.
+func ptr[T any](v T) *T {
+	return &v
+}
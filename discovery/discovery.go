@@ -0,0 +1,594 @@
+// Package discovery finds Terraform components and their variable files in a
+// file system, and generates Atlantis repo config from them.
+//
+// It is the library behind the `generate` command, and can be used by other
+// tools without shelling out to it:
+//
+//	cfg, err := discovery.Generate(ctx, os.DirFS("."), discovery.Options{
+//		Strategy: discovery.DirectoryStrategy{},
+//		RepoCfg:  repocfg.Options{Autoplan: true},
+//	})
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/3bbbeau/tfvars-atlantis-config/repocfg"
+	"github.com/runatlantis/atlantis/server/core/config/raw"
+	"go.uber.org/zap"
+)
+
+const (
+	TF_EXT          = ".tf"
+	TF_JSON_EXT     = ".tf.json"
+	TOFU_EXT        = ".tofu"
+	TOFU_JSON_EXT   = ".tofu.json"
+	TFVARS_EXT      = ".tfvars"
+	TFVARS_JSON_EXT = ".tfvars.json"
+
+	// ENV_PLACEHOLDER is substituted with the environment name in the
+	// backend config pattern.
+	ENV_PLACEHOLDER = "{env}"
+)
+
+// TERRAFORM_EXTS are the extensions of the files that make a directory a
+// Terraform component, which may be written for Terraform or OpenTofu.
+var TERRAFORM_EXTS = []string{TF_EXT, TF_JSON_EXT, TOFU_EXT, TOFU_JSON_EXT}
+
+// Claim describes which Terraform component, if any, claimed a variable file
+// during discovery, and the reason for it.
+type Claim struct {
+	VarFile     string
+	Component   string
+	Environment string
+	Reason      string
+}
+
+// Claimed returns true if the variable file belongs to a component.
+func (c Claim) Claimed() bool {
+	return c.Component != ""
+}
+
+// Options represents the configuration for discovering Terraform components
+// and generating an Atlantis RepoCfg from them.
+type Options struct {
+	// Strategy determines the environment of each variable file. Defaults to
+	// FilenameStrategy.
+	Strategy Strategy
+
+	// BackendConfigPattern is the path, relative to each component, of the
+	// per-environment backend config files, where ENV_PLACEHOLDER is
+	// substituted with the environment name, e.g. "backend/{env}.hcl".
+	// Backend configs are not discovered if empty.
+	BackendConfigPattern string
+
+	// MaxVarFileDepth is the maximum number of directories a variable file
+	// may be nested below the component claiming it. 0 means no limit.
+	MaxVarFileDepth int
+
+	// Concurrency is the maximum number of components inspected at once.
+	// Defaults to GOMAXPROCS.
+	Concurrency int
+
+	// RepoCfg is used to generate the Atlantis RepoCfg from the discovered
+	// components.
+	RepoCfg repocfg.Options
+
+	// Logger is used for debug logs and warnings. Defaults to a no-op logger.
+	Logger *zap.Logger
+
+	// Root is the directory, relative to the root of fsys, components are
+	// discovered in. Paths remain relative to the root of fsys, so that local
+	// modules outside of it are still resolved. Defaults to the root of fsys.
+	Root string
+}
+
+// root returns the configured Root, or the root of fsys.
+func (opts Options) root() (string, error) {
+	if opts.Root == "" {
+		return ".", nil
+	}
+
+	root := path.Clean(opts.Root)
+	if !fs.ValidPath(root) {
+		return "", fmt.Errorf("root %q must be a relative path within the file system", opts.Root)
+	}
+	return root, nil
+}
+
+// logger returns the configured logger, or a no-op logger.
+func (opts Options) logger() *zap.Logger {
+	if opts.Logger == nil {
+		return zap.NewNop()
+	}
+	return opts.Logger
+}
+
+// concurrency returns the configured Concurrency, or GOMAXPROCS.
+func (opts Options) concurrency() int {
+	if opts.Concurrency < 1 {
+		return runtime.GOMAXPROCS(0)
+	}
+	return opts.Concurrency
+}
+
+// strategy returns the configured Strategy, or FilenameStrategy.
+func (opts Options) strategy() Strategy {
+	if opts.Strategy == nil {
+		return FilenameStrategy{}
+	}
+	return opts.Strategy
+}
+
+// Generate discovers the Terraform components in fsys and returns the Atlantis
+// RepoCfg with their projects. See Discover.
+func Generate(ctx context.Context, fsys fs.FS, opts Options) (*repocfg.ExtRawRepoCfg, error) {
+	result, err := generate(ctx, fsys, opts)
+	if err != nil {
+		return nil, err
+	}
+	return result.Config, nil
+}
+
+// Result is the outcome of generating an Atlantis RepoCfg, with what was
+// discovered to generate it, e.g. to report on a run.
+type Result struct {
+	// Config is the generated Atlantis RepoCfg.
+	Config *repocfg.ExtRawRepoCfg
+
+	// Components are the discovered components, in the order of the roots.
+	Components []repocfg.Component
+
+	// Ignored are the claims of the variable files no component claimed.
+	Ignored []Claim
+}
+
+// generate discovers the Terraform components in fsys and generates the
+// Atlantis RepoCfg with their projects.
+func generate(ctx context.Context, fsys fs.FS, opts Options) (*Result, error) {
+	components, ignored, err := discover(ctx, fsys, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := repocfg.NewRepoCfg(components, opts.RepoCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range cfg.Projects {
+		opts.logger().Debug("generated project", zap.String("project", *p.Name), zap.String("component", *p.Dir))
+	}
+	return &Result{
+		Config:     cfg,
+		Components: components,
+		Ignored:    ignored,
+	}, nil
+}
+
+// GenerateRoots generates the Atlantis RepoCfg for each of the roots, each
+// discovered with its own Options, and combines their projects and workflows
+// into one. The repo level settings are those of the first root. Paths are
+// relative to the root of fsys, so roots must not overlap.
+func GenerateRoots(ctx context.Context, fsys fs.FS, roots []Options) (*repocfg.ExtRawRepoCfg, error) {
+	result, err := Run(ctx, fsys, roots)
+	if err != nil {
+		return nil, err
+	}
+	return result.Config, nil
+}
+
+// Run generates the Atlantis RepoCfg for each of the roots like
+// GenerateRoots, returning the combined config along with what was
+// discovered in every root.
+func Run(ctx context.Context, fsys fs.FS, roots []Options) (*Result, error) {
+	seen, err := checkRoots(roots)
+	if err != nil {
+		return nil, err
+	}
+
+	var combined *Result
+	for i, opts := range roots {
+		result, err := generate(ctx, fsys, opts)
+		if err != nil {
+			return nil, fmt.Errorf("root %s: %w", seen[i], err)
+		}
+
+		if combined == nil {
+			combined = result
+			continue
+		}
+
+		combined.Components = append(combined.Components, result.Components...)
+		combined.Ignored = append(combined.Ignored, result.Ignored...)
+		combined.Config.Projects = append(combined.Config.Projects, result.Config.Projects...)
+		for name, w := range result.Config.Workflows {
+			if combined.Config.Workflows == nil {
+				combined.Config.Workflows = map[string]raw.Workflow{}
+			}
+			combined.Config.Workflows[name] = w
+		}
+	}
+
+	// Each root was validated on its own, but project names may clash
+	// across roots.
+	if err := combined.Config.Validate(roots[0].RepoCfg); err != nil {
+		return nil, err
+	}
+
+	return combined, nil
+}
+
+// DiscoverRoots discovers the Terraform components of each of the roots, each
+// with its own Options, in the order of the roots. See Discover.
+func DiscoverRoots(ctx context.Context, fsys fs.FS, roots []Options) ([]repocfg.Component, error) {
+	seen, err := checkRoots(roots)
+	if err != nil {
+		return nil, err
+	}
+
+	discovered := []repocfg.Component{}
+	for i, opts := range roots {
+		components, err := Discover(ctx, fsys, opts)
+		if err != nil {
+			return nil, fmt.Errorf("root %s: %w", seen[i], err)
+		}
+		discovered = append(discovered, components...)
+	}
+	return discovered, nil
+}
+
+// ExplainRoots explains the claims of the variable files of each of the
+// roots, each with its own Options, in the order of the roots. See Explain.
+func ExplainRoots(ctx context.Context, fsys fs.FS, roots []Options) ([]Claim, error) {
+	seen, err := checkRoots(roots)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := []Claim{}
+	for i, opts := range roots {
+		explained, err := Explain(ctx, fsys, opts)
+		if err != nil {
+			return nil, fmt.Errorf("root %s: %w", seen[i], err)
+		}
+		claims = append(claims, explained...)
+	}
+	return claims, nil
+}
+
+// checkRoots returns the directory of each of the roots, or an error if there
+// are none or any of them overlap.
+func checkRoots(roots []Options) ([]string, error) {
+	if len(roots) == 0 {
+		return nil, fmt.Errorf("no roots to discover")
+	}
+
+	seen := make([]string, 0, len(roots))
+	for _, opts := range roots {
+		root, err := opts.root()
+		if err != nil {
+			return nil, err
+		}
+		for _, other := range seen {
+			if within(root, other) || within(other, root) {
+				return nil, fmt.Errorf("roots %s and %s overlap", other, root)
+			}
+		}
+		seen = append(seen, root)
+	}
+	return seen, nil
+}
+
+// within returns true if dir is root or one of its subdirectories.
+func within(dir, root string) bool {
+	return root == "." || dir == root || strings.HasPrefix(dir, root+"/")
+}
+
+// Discover walks the file system and creates a slice of Terraform components
+// and their dependencies based on the .tfvars files in the directory and
+// subdirectories.
+//
+// Any directory containing a .tf, .tf.json, .tofu or .tofu.json file is a
+// Terraform component. Each variable file is claimed by the nearest component
+// at or above its directory, so the .tfvars files of a nested component are
+// never attributed to the component containing it. See Explain for the reason
+// each variable file was, or was not, claimed.
+//
+// For example, given a file system rooted at:
+//
+//	.
+//	├── components
+//	│   ├── component1
+//	│   │   ├── main.tf
+//	│   │   └── dev.tfvars
+//	│   └── component2
+//	│       ├── extraVars
+//	│       │   └── stg.tfvars
+//	│       ├── nested
+//	│       │   ├── main.tf
+//	│       │   └── dev.tfvars
+//	│       ├── main.tf
+//	│       └── dev.tfvars
+//
+// Then, the resulting slice of components would look like:
+//
+//	Path:	"components/component1"
+//	VarFiles:	"components/component1/dev.tfvars" (dev)
+//
+//	Path:	"components/component2"
+//	VarFiles:	"components/component2/dev.tfvars" (dev), "components/component2/extraVars/stg.tfvars" (stg)
+//
+//	Path:	"components/component2/nested"
+//	VarFiles:	"components/component2/nested/dev.tfvars" (dev)
+//
+// The environment of each var file is determined by the Strategy in opts.
+// Paths are slash separated and relative to the root of fsys, which defaults
+// to the current directory if nil. Components are ordered by path.
+//
+// Symbolic links to directories are not followed, while symbolic links to
+// files are treated as the files they are named as.
+func Discover(ctx context.Context, fsys fs.FS, opts Options) ([]repocfg.Component, error) {
+	components, _, err := discover(ctx, fsys, opts)
+	return components, err
+}
+
+// discover finds the Terraform components in fsys, see Discover, and the
+// claims of the variable files none of them claimed.
+func discover(ctx context.Context, fsys fs.FS, opts Options) ([]repocfg.Component, []Claim, error) {
+	logger := opts.logger()
+
+	if fsys == nil {
+		fsys = os.DirFS(".")
+	}
+
+	root, err := opts.root()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	idx, err := newIndex(ctx, fsys, root)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	varFiles := map[string][]repocfg.VarFile{}
+	ignored := []Claim{}
+	for _, c := range idx.claims(opts.strategy(), opts.MaxVarFileDepth) {
+		if !c.Claimed() {
+			ignored = append(ignored, c)
+			logger.Debug("ignoring var file", zap.String("var_file", c.VarFile), zap.String("reason", c.Reason))
+			continue
+		}
+		logger.Debug("claimed var file",
+			zap.String("component", c.Component),
+			zap.String("var_file", c.VarFile),
+			zap.String("environment", c.Environment),
+			zap.String("reason", c.Reason),
+		)
+
+		// The var files of the same environment are planned together in a
+		// single project.
+		for _, existing := range varFiles[c.Component] {
+			if existing.Environment == c.Environment {
+				logger.Debug("grouping var files of the same environment",
+					zap.String("component", c.Component),
+					zap.Strings("var_files", []string{existing.Path, c.VarFile}),
+					zap.String("environment", c.Environment),
+				)
+				break
+			}
+		}
+		varFiles[c.Component] = append(varFiles[c.Component], repocfg.VarFile{
+			Path:        c.VarFile,
+			Environment: c.Environment,
+		})
+	}
+
+	// Components without variable files are included, whether they have any
+	// projects is decided when generating them.
+	discovered := []repocfg.Component{}
+	for _, dir := range idx.components {
+		discovered = append(discovered, repocfg.Component{
+			Path:       dir,
+			VarFiles:   varFiles[dir],
+			Extensions: idx.extensions[dir],
+		})
+	}
+
+	// Any work reading the files of a component is done concurrently, as
+	// each component is independent of the others.
+	err = forEach(ctx, opts.concurrency(), len(discovered), func(i int) error {
+		return inspect(fsys, logger, &discovered[i], opts)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Components called as local modules by other components are marked, as
+	// they get no default project without var files.
+	called := map[string]bool{}
+	for _, c := range discovered {
+		for _, m := range c.Modules {
+			if m != c.Path {
+				called[m] = true
+			}
+		}
+	}
+	for i, c := range discovered {
+		if called[c.Path] {
+			discovered[i].IsModule = true
+			logger.Debug("called as a local module", zap.String("component", c.Path))
+		}
+	}
+
+	return discovered, ignored, nil
+}
+
+// inspect completes a component from the files within it.
+func inspect(fsys fs.FS, logger *zap.Logger, c *repocfg.Component, opts Options) error {
+	logger = logger.With(zap.String("component", c.Path))
+
+	modules, err := discoverModules(fsys, logger, c.Path)
+	if err != nil {
+		return err
+	}
+	c.Modules = modules
+
+	for i, v := range c.VarFiles {
+		annotations, err := discoverAnnotations(fsys, logger, v.Path)
+		if err != nil {
+			return err
+		}
+		if annotations.Skip {
+			logger.Debug("skipping var file annotated with "+ANNOTATION_PREFIX+"skip", zap.String("var_file", v.Path))
+		}
+		c.VarFiles[i].Annotations = annotations
+	}
+
+	tfVersion, versionFile, err := discoverTerraformVersion(fsys, logger, c.Path)
+	if err != nil {
+		return err
+	}
+	switch {
+	case versionFile != "":
+		logger.Debug("using terraform version from version file", zap.String("terraform_version", tfVersion), zap.String("file", versionFile))
+		c.TerraformVersion = tfVersion
+		c.TerraformVersionFile = versionFile
+	case opts.RepoCfg.DefaultTerraformVersion != "":
+		logger.Debug("using the default terraform version", zap.String("terraform_version", opts.RepoCfg.DefaultTerraformVersion))
+	}
+
+	if opts.BackendConfigPattern != "" {
+		backendConfigs, err := discoverBackendConfigs(fsys, logger, *c, opts.BackendConfigPattern)
+		if err != nil {
+			return err
+		}
+		c.BackendConfigs = backendConfigs
+	}
+
+	return nil
+}
+
+// forEach calls fn with each index from 0 to n, from at most `workers`
+// goroutines at a time. The first error returned by fn is returned once all
+// calls have finished, and no further calls are made after it.
+func forEach(ctx context.Context, workers, n int, fn func(int) error) error {
+	var (
+		wg    sync.WaitGroup
+		once  sync.Once
+		first error
+	)
+	failed := make(chan struct{})
+	sem := make(chan struct{}, workers)
+
+loop:
+	for i := 0; i < n; i++ {
+		select {
+		case <-ctx.Done():
+			break loop
+		case <-failed:
+			break loop
+		case sem <- struct{}{}:
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			if err := fn(i); err != nil {
+				once.Do(func() {
+					first = err
+					close(failed)
+				})
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if first != nil {
+		return first
+	}
+	return ctx.Err()
+}
+
+// Explain walks the file system like Discover, and returns a Claim for every
+// variable file found, describing which component claimed it and why.
+func Explain(ctx context.Context, fsys fs.FS, opts Options) ([]Claim, error) {
+	if fsys == nil {
+		fsys = os.DirFS(".")
+	}
+
+	root, err := opts.root()
+	if err != nil {
+		return nil, err
+	}
+
+	idx, err := newIndex(ctx, fsys, root)
+	if err != nil {
+		return nil, err
+	}
+
+	return idx.claims(opts.strategy(), opts.MaxVarFileDepth), nil
+}
+
+// discoverBackendConfigs finds the backend config files for a component
+// matching the pattern, relative to the component, where the environment
+// name is substituted for ENV_PLACEHOLDER, e.g. "backend/{env}.hcl".
+//
+// Backend configs are paired with the component's variable files by their
+// environment name. An environment that only has one of the two is logged as
+// a warning, unless the component has no backend configs at all, as not
+// every component of a repo needs one.
+func discoverBackendConfigs(fsys fs.FS, logger *zap.Logger, c repocfg.Component, pattern string) (map[string]string, error) {
+	if !strings.Contains(pattern, ENV_PLACEHOLDER) {
+		return nil, fmt.Errorf("backend config pattern %q must contain %s", pattern, ENV_PLACEHOLDER)
+	}
+
+	// The environment name is extracted from the file name with the
+	// placeholder replaced by a single path segment.
+	re, err := regexp.Compile("^" + strings.ReplaceAll(regexp.QuoteMeta(pattern), regexp.QuoteMeta(ENV_PLACEHOLDER), "([^/]+)") + "$")
+	if err != nil {
+		return nil, fmt.Errorf("backend config pattern %q: %w", pattern, err)
+	}
+
+	matches, err := fs.Glob(fsys, path.Join(c.Path, strings.ReplaceAll(pattern, ENV_PLACEHOLDER, "*")))
+	if err != nil {
+		return nil, fmt.Errorf("backend config pattern %q: %w", pattern, err)
+	}
+
+	backendConfigs := map[string]string{}
+	for _, match := range matches {
+		submatches := re.FindStringSubmatch(relativeVarFile(c.Path, match))
+		if submatches == nil {
+			continue
+		}
+
+		logger.Debug("found backend config", zap.String("backend_config", match), zap.String("environment", submatches[1]))
+		backendConfigs[submatches[1]] = match
+	}
+
+	environments := map[string]bool{}
+	for _, v := range c.VarFiles {
+		environments[v.Environment] = true
+		if _, ok := backendConfigs[v.Environment]; !ok && len(backendConfigs) > 0 {
+			logger.Warn("no backend config for the environment of var file", zap.String("var_file", v.Path), zap.String("environment", v.Environment))
+		}
+	}
+	for env, backendConfig := range backendConfigs {
+		if !environments[env] {
+			logger.Warn("no var file for the environment of backend config", zap.String("backend_config", backendConfig), zap.String("environment", env))
+		}
+	}
+
+	return backendConfigs, nil
+}
@@ -0,0 +1,728 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/3bbbeau/tfvars-atlantis-config/repocfg"
+	"github.com/google/go-cmp/cmp"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// Tests the Discover function against in-memory file systems.
+func Test_Discover(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		fsys fstest.MapFS
+		opts Options
+		want []repocfg.Component
+	}{
+		{
+			name: "Empty",
+			fsys: fstest.MapFS{},
+			want: []repocfg.Component{},
+		},
+		{
+			name: "EmptyDirs",
+			fsys: fstest.MapFS{
+				"empty":                {Mode: fs.ModeDir},
+				"component/nested":     {Mode: fs.ModeDir},
+				"component/main.tf":    {},
+				"component/dev.tfvars": {},
+			},
+			want: []repocfg.Component{
+				{
+					Path:       "component",
+					Extensions: []string{".tf"},
+					VarFiles:   []repocfg.VarFile{{Path: "component/dev.tfvars", Environment: "dev"}},
+				},
+			},
+		},
+		{
+			name: "RootComponent",
+			fsys: fstest.MapFS{
+				"main.tf":    {},
+				"dev.tfvars": {},
+			},
+			want: []repocfg.Component{
+				{
+					Path:       ".",
+					Extensions: []string{".tf"},
+					VarFiles:   []repocfg.VarFile{{Path: "dev.tfvars", Environment: "dev"}},
+				},
+			},
+		},
+		{
+			name: "NoVarFiles",
+			fsys: fstest.MapFS{
+				"component/main.tf": {},
+			},
+			want: []repocfg.Component{
+				{Path: "component", Extensions: []string{".tf"}},
+			},
+		},
+		{
+			name: "NoComponent",
+			fsys: fstest.MapFS{
+				"vars/dev.tfvars": {},
+			},
+			want: []repocfg.Component{},
+		},
+		{
+			name: "TfvarsJSON",
+			fsys: fstest.MapFS{
+				"component/main.tf":         {},
+				"component/dev.tfvars":      {},
+				"component/stg.tfvars.json": {},
+			},
+			want: []repocfg.Component{
+				{
+					Path:       "component",
+					Extensions: []string{".tf"},
+					VarFiles: []repocfg.VarFile{
+						{Path: "component/dev.tfvars", Environment: "dev"},
+						{Path: "component/stg.tfvars.json", Environment: "stg"},
+					},
+				},
+			},
+		},
+		{
+			name: "TerraformJSON",
+			fsys: fstest.MapFS{
+				"cdk/main.tf.json":        {},
+				"cdk/dev.tfvars.json":     {},
+				"mixed/main.tf":           {},
+				"mixed/generated.tf.json": {},
+				"mixed/dev.tfvars":        {},
+			},
+			want: []repocfg.Component{
+				{
+					Path:       "cdk",
+					Extensions: []string{".tf.json"},
+					VarFiles:   []repocfg.VarFile{{Path: "cdk/dev.tfvars.json", Environment: "dev"}},
+				},
+				{
+					Path:       "mixed",
+					Extensions: []string{".tf", ".tf.json"},
+					VarFiles:   []repocfg.VarFile{{Path: "mixed/dev.tfvars", Environment: "dev"}},
+				},
+			},
+		},
+		{
+			name: "OpenTofu",
+			fsys: fstest.MapFS{
+				"tofu/main.tofu":      {},
+				"tofu/vars.tofu.json": {},
+				"tofu/dev.tfvars":     {},
+				"mixed/main.tofu":     {},
+				"mixed/providers.tf":  {},
+				"mixed/dev.tfvars":    {},
+			},
+			want: []repocfg.Component{
+				{
+					Path:       "mixed",
+					Extensions: []string{".tf", ".tofu"},
+					VarFiles:   []repocfg.VarFile{{Path: "mixed/dev.tfvars", Environment: "dev"}},
+				},
+				{
+					Path:       "tofu",
+					Extensions: []string{".tofu", ".tofu.json"},
+					VarFiles:   []repocfg.VarFile{{Path: "tofu/dev.tfvars", Environment: "dev"}},
+				},
+			},
+		},
+		{
+			name: "NestedVarFiles",
+			fsys: fstest.MapFS{
+				"components/component2/main.tf":              {},
+				"components/component2/dev.tfvars":           {},
+				"components/component2/extraVars/stg.tfvars": {},
+			},
+			want: []repocfg.Component{
+				{
+					Path:       "components/component2",
+					Extensions: []string{".tf"},
+					VarFiles: []repocfg.VarFile{
+						{Path: "components/component2/dev.tfvars", Environment: "dev"},
+						{Path: "components/component2/extraVars/stg.tfvars", Environment: "stg"},
+					},
+				},
+			},
+		},
+		{
+			name: "NestedComponents",
+			fsys: fstest.MapFS{
+				"parent/main.tf":          {},
+				"parent/dev.tfvars":       {},
+				"parent/child/main.tf":    {},
+				"parent/child/stg.tfvars": {},
+			},
+			want: []repocfg.Component{
+				{
+					Path:       "parent",
+					Extensions: []string{".tf"},
+					VarFiles:   []repocfg.VarFile{{Path: "parent/dev.tfvars", Environment: "dev"}},
+				},
+				{
+					Path:       "parent/child",
+					Extensions: []string{".tf"},
+					VarFiles:   []repocfg.VarFile{{Path: "parent/child/stg.tfvars", Environment: "stg"}},
+				},
+			},
+		},
+		{
+			name: "NestedComponentSortedBeforeParentFiles",
+			fsys: fstest.MapFS{
+				"parent/a.tf":               {},
+				"parent/dev.tfvars":         {},
+				"parent/z/main.tf":          {},
+				"parent/z/vars/stg.tfvars":  {},
+				"parent/z/other/prd.tfvars": {},
+			},
+			want: []repocfg.Component{
+				{
+					Path:       "parent",
+					Extensions: []string{".tf"},
+					VarFiles:   []repocfg.VarFile{{Path: "parent/dev.tfvars", Environment: "dev"}},
+				},
+				{
+					Path:       "parent/z",
+					Extensions: []string{".tf"},
+					VarFiles: []repocfg.VarFile{
+						{Path: "parent/z/other/prd.tfvars", Environment: "prd"},
+						{Path: "parent/z/vars/stg.tfvars", Environment: "stg"},
+					},
+				},
+			},
+		},
+		{
+			name: "MaxVarFileDepth",
+			fsys: fstest.MapFS{
+				"component/main.tf":              {},
+				"component/dev.tfvars":           {},
+				"component/vars/stg.tfvars":      {},
+				"component/vars/deep/prd.tfvars": {},
+			},
+			opts: Options{MaxVarFileDepth: 1},
+			want: []repocfg.Component{
+				{
+					Path:       "component",
+					Extensions: []string{".tf"},
+					VarFiles: []repocfg.VarFile{
+						{Path: "component/dev.tfvars", Environment: "dev"},
+						{Path: "component/vars/stg.tfvars", Environment: "stg"},
+					},
+				},
+			},
+		},
+		{
+			name: "DirectoryStrategy",
+			fsys: fstest.MapFS{
+				"component/main.tf":                   {},
+				"component/terraform.tfvars":          {},
+				"component/envs/dev/terraform.tfvars": {},
+				"component/envs/prd/terraform.tfvars": {},
+			},
+			opts: Options{Strategy: DirectoryStrategy{}},
+			want: []repocfg.Component{
+				{
+					Path:       "component",
+					Extensions: []string{".tf"},
+					VarFiles: []repocfg.VarFile{
+						{Path: "component/envs/dev/terraform.tfvars", Environment: "dev"},
+						{Path: "component/envs/prd/terraform.tfvars", Environment: "prd"},
+					},
+				},
+			},
+		},
+		{
+			name: "DirectoryStrategySeveralVarFiles",
+			fsys: fstest.MapFS{
+				"component/main.tf":                   {},
+				"component/envs/dev/terraform.tfvars": {},
+				"component/envs/dev/extra.tfvars":     {},
+			},
+			opts: Options{Strategy: DirectoryStrategy{}},
+			want: []repocfg.Component{
+				{
+					Path:       "component",
+					Extensions: []string{".tf"},
+					VarFiles: []repocfg.VarFile{
+						{Path: "component/envs/dev/extra.tfvars", Environment: "dev"},
+						{Path: "component/envs/dev/terraform.tfvars", Environment: "dev"},
+					},
+				},
+			},
+		},
+		{
+			name: "BackendConfigs",
+			fsys: fstest.MapFS{
+				"component/main.tf":           {},
+				"component/dev.tfvars":        {},
+				"component/prd.tfvars":        {},
+				"component/backend/dev.hcl":   {},
+				"component/backend/other.txt": {},
+			},
+			opts: Options{BackendConfigPattern: "backend/{env}.hcl"},
+			want: []repocfg.Component{
+				{
+					Path:       "component",
+					Extensions: []string{".tf"},
+					VarFiles: []repocfg.VarFile{
+						{Path: "component/dev.tfvars", Environment: "dev"},
+						{Path: "component/prd.tfvars", Environment: "prd"},
+					},
+					BackendConfigs: map[string]string{"dev": "component/backend/dev.hcl"},
+				},
+			},
+		},
+		{
+			name: "Symlinks",
+			fsys: fstest.MapFS{
+				"component/main.tf":    {},
+				"component/dev.tfvars": {},
+				// Symbolic links to files are discovered by their name
+				"linked/main.tf":    {},
+				"linked/stg.tfvars": {Data: []byte("../component/dev.tfvars"), Mode: fs.ModeSymlink},
+				// Symbolic links to directories are not followed
+				"aliased": {Data: []byte("component"), Mode: fs.ModeSymlink},
+			},
+			want: []repocfg.Component{
+				{
+					Path:       "component",
+					Extensions: []string{".tf"},
+					VarFiles:   []repocfg.VarFile{{Path: "component/dev.tfvars", Environment: "dev"}},
+				},
+				{
+					Path:       "linked",
+					Extensions: []string{".tf"},
+					VarFiles:   []repocfg.VarFile{{Path: "linked/stg.tfvars", Environment: "stg"}},
+				},
+			},
+		},
+		{
+			name: "LocalModules",
+			fsys: fstest.MapFS{
+				"net/main.tf":                {Data: []byte("module \"vpc\" {\n  source = \"../modules/vpc\"\n}\n")},
+				"net/dev.tfvars":             {},
+				"modules/vpc/main.tf":        {Data: []byte("module \"subnets\" {\n  source = \"../subnets\"\n}\n")},
+				"modules/subnets/main.tf":    {},
+				"modules/subnets/dev.tfvars": {},
+			},
+			want: []repocfg.Component{
+				{
+					Path:       "modules/subnets",
+					Extensions: []string{".tf"},
+					VarFiles:   []repocfg.VarFile{{Path: "modules/subnets/dev.tfvars", Environment: "dev"}},
+					IsModule:   true,
+				},
+				{
+					Path:       "modules/vpc",
+					Extensions: []string{".tf"},
+					Modules:    []string{"modules/subnets"},
+					IsModule:   true,
+				},
+				{
+					Path:       "net",
+					Extensions: []string{".tf"},
+					VarFiles:   []repocfg.VarFile{{Path: "net/dev.tfvars", Environment: "dev"}},
+					Modules:    []string{"modules/vpc"},
+				},
+			},
+		},
+		{
+			name: "Root",
+			fsys: fstest.MapFS{
+				"outside/main.tf":             {},
+				"outside/dev.tfvars":          {},
+				"infra/aws/main.tf":           {},
+				"infra/aws/dev.tfvars":        {},
+				"infra/aws/nested/stg.tfvars": {},
+				"infra/awsnotroot/main.tf":    {},
+				"infra/awsnotroot/prd.tfvars": {},
+			},
+			opts: Options{Root: "infra/aws/"},
+			want: []repocfg.Component{
+				{
+					Path:       "infra/aws",
+					Extensions: []string{".tf"},
+					VarFiles: []repocfg.VarFile{
+						{Path: "infra/aws/dev.tfvars", Environment: "dev"},
+						{Path: "infra/aws/nested/stg.tfvars", Environment: "stg"},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		got, err := Discover(context.Background(), tc.fsys, tc.opts)
+		if err != nil {
+			t.Errorf("%s: Discover() error: %s", tc.name, err)
+			continue
+		}
+
+		if !cmp.Equal(got, tc.want) {
+			t.Errorf(`%s: Discover()
+			diff %s`, tc.name, cmp.Diff(got, tc.want))
+		}
+	}
+}
+
+// Tests the Discover function returns an error for an invalid backend config
+// pattern.
+func Test_DiscoverBackendConfigPattern(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		"component/main.tf":    {},
+		"component/dev.tfvars": {},
+	}
+
+	_, err := Discover(context.Background(), fsys, Options{BackendConfigPattern: "backend/dev.hcl"})
+	if err == nil {
+		t.Errorf("Discover() expected error for pattern without %s", ENV_PLACEHOLDER)
+	}
+}
+
+// Tests the discoverBackendConfigs function pairs backend configs with var
+// files by environment, only warning about an environment without a backend
+// config if the component has any.
+func Test_DiscoverBackendConfigs(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		"aws/net/main.tf":         {},
+		"aws/net/backend/dev.hcl": {},
+		"aws/net/backend/stg.hcl": {},
+		"aws/net/dev.tfvars":      {},
+		"aws/net/prd.tfvars":      {},
+		"gcp/net/main.tf":         {},
+		"gcp/net/dev.tfvars":      {},
+		"gcp/net/prd.tfvars":      {},
+	}
+
+	tests := []struct {
+		component    repocfg.Component
+		want         map[string]string
+		wantWarnings []string
+	}{
+		{
+			component: repocfg.Component{
+				Path:     "aws/net",
+				VarFiles: []repocfg.VarFile{{Path: "aws/net/dev.tfvars", Environment: "dev"}, {Path: "aws/net/prd.tfvars", Environment: "prd"}},
+			},
+			want: map[string]string{"dev": "aws/net/backend/dev.hcl", "stg": "aws/net/backend/stg.hcl"},
+			wantWarnings: []string{
+				"no backend config for the environment of var file aws/net/prd.tfvars",
+				"no var file for the environment of backend config aws/net/backend/stg.hcl",
+			},
+		},
+		{
+			component: repocfg.Component{
+				Path:     "gcp/net",
+				VarFiles: []repocfg.VarFile{{Path: "gcp/net/dev.tfvars", Environment: "dev"}, {Path: "gcp/net/prd.tfvars", Environment: "prd"}},
+			},
+			want: map[string]string{},
+		},
+	}
+
+	for _, tc := range tests {
+		core, logs := observer.New(zap.WarnLevel)
+		got, err := discoverBackendConfigs(fsys, zap.New(core), tc.component, "backend/{env}.hcl")
+		if err != nil {
+			t.Errorf("%s: discoverBackendConfigs() error: %s", tc.component.Path, err)
+			continue
+		}
+		if !cmp.Equal(got, tc.want) {
+			t.Errorf(`%s: discoverBackendConfigs()
+			diff %s`, tc.component.Path, cmp.Diff(got, tc.want))
+		}
+
+		var gotWarnings []string
+		for _, entry := range logs.All() {
+			file := entry.ContextMap()["var_file"]
+			if file == nil {
+				file = entry.ContextMap()["backend_config"]
+			}
+			gotWarnings = append(gotWarnings, fmt.Sprintf("%s %s", entry.Message, file))
+		}
+		if !cmp.Equal(gotWarnings, tc.wantWarnings) {
+			t.Errorf(`%s: discoverBackendConfigs() warnings
+			diff %s`, tc.component.Path, cmp.Diff(gotWarnings, tc.wantWarnings))
+		}
+	}
+}
+
+// Tests the Discover function rejects roots outside of the file system.
+func Test_DiscoverRootErrors(t *testing.T) {
+	t.Parallel()
+
+	for _, root := range []string{"/infra", "../infra", "infra/../.."} {
+		_, err := Discover(context.Background(), fstest.MapFS{}, Options{Root: root})
+		if err == nil {
+			t.Errorf("Discover() expected error for root %s", root)
+		}
+	}
+}
+
+// Tests the GenerateRoots function combines the projects of each root, each
+// generated with its own options, and rejects overlapping roots or project
+// names clashing across roots.
+func Test_GenerateRoots(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		"aws/network/main.tf":                   {},
+		"aws/network/dev.tfvars":                {},
+		"gcp/network/main.tf":                   {},
+		"gcp/network/envs/stg/terraform.tfvars": {},
+	}
+
+	got, err := GenerateRoots(context.Background(), fsys, []Options{
+		{Root: "aws", RepoCfg: repocfg.Options{Automerge: true}},
+		{Root: "gcp", Strategy: DirectoryStrategy{}, RepoCfg: repocfg.Options{DefaultTerraformVersion: "1.9.0"}},
+	})
+	if err != nil {
+		t.Fatalf("GenerateRoots() error: %s", err)
+	}
+
+	type project struct{ Name, Dir, TerraformVersion string }
+	var projects []project
+	for _, p := range got.Projects {
+		projects = append(projects, project{*p.Name, *p.Dir, deref(p.TerraformVersion)})
+	}
+	want := []project{
+		{"aws-network-dev", "aws/network", ""},
+		{"gcp-network-stg", "gcp/network", "1.9.0"},
+	}
+	if !cmp.Equal(projects, want) {
+		t.Errorf(`GenerateRoots()
+		diff %s`, cmp.Diff(projects, want))
+	}
+	if !*got.Automerge {
+		t.Errorf("GenerateRoots() expected the repo settings of the first root")
+	}
+
+	errTests := []struct {
+		name  string
+		roots []Options
+	}{
+		{name: "NoRoots"},
+		{name: "Overlapping", roots: []Options{{Root: "aws"}, {Root: "aws/network"}}},
+		{name: "OverlappingRepoRoot", roots: []Options{{Root: "gcp"}, {}}},
+		{name: "Duplicate", roots: []Options{{Root: "aws"}, {Root: "aws/"}}},
+	}
+	for _, tc := range errTests {
+		if _, err := GenerateRoots(context.Background(), fsys, tc.roots); err == nil {
+			t.Errorf("%s: GenerateRoots() expected error", tc.name)
+		}
+		if _, err := DiscoverRoots(context.Background(), fsys, tc.roots); err == nil {
+			t.Errorf("%s: DiscoverRoots() expected error", tc.name)
+		}
+		if _, err := ExplainRoots(context.Background(), fsys, tc.roots); err == nil {
+			t.Errorf("%s: ExplainRoots() expected error", tc.name)
+		}
+	}
+}
+
+// Tests the Run, DiscoverRoots and ExplainRoots functions give each root's
+// projects, components and claims in the order of the roots, each project
+// generated with the options of its root.
+func Test_RunRoots(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		"aws/network/main.tf":                   {},
+		"aws/network/dev.tfvars":                {},
+		"gcp/network/main.tf":                   {},
+		"gcp/network/envs/stg/terraform.tfvars": {},
+	}
+	roots := []Options{
+		{Root: "gcp", Strategy: DirectoryStrategy{}, RepoCfg: repocfg.Options{UseWorkspaces: true}},
+		{Root: "aws"},
+	}
+
+	result, err := Run(context.Background(), fsys, roots)
+	if err != nil {
+		t.Fatalf("Run() error: %s", err)
+	}
+	var got []string
+	for _, p := range result.Config.Projects {
+		got = append(got, fmt.Sprintf("%s workspace=%q", *p.Name, deref(p.Workspace)))
+	}
+	want := []string{`gcp-network-stg workspace="stg"`, `aws-network-dev workspace=""`}
+	if !cmp.Equal(got, want) {
+		t.Errorf(`Run()
+		diff %s`, cmp.Diff(got, want))
+	}
+
+	components, err := DiscoverRoots(context.Background(), fsys, roots)
+	if err != nil {
+		t.Fatalf("DiscoverRoots() error: %s", err)
+	}
+	if !cmp.Equal(components, result.Components) {
+		t.Errorf(`DiscoverRoots()
+		diff %s`, cmp.Diff(components, result.Components))
+	}
+
+	claims, err := ExplainRoots(context.Background(), fsys, roots)
+	if err != nil {
+		t.Fatalf("ExplainRoots() error: %s", err)
+	}
+	got = nil
+	for _, c := range claims {
+		got = append(got, c.Component+" "+c.Environment)
+	}
+	want = []string{"gcp/network stg", "aws/network dev"}
+	if !cmp.Equal(got, want) {
+		t.Errorf(`ExplainRoots()
+		diff %s`, cmp.Diff(got, want))
+	}
+}
+
+// deref returns the value of p, or the zero value if nil.
+func deref[T any](p *T) T {
+	var v T
+	if p != nil {
+		v = *p
+	}
+	return v
+}
+
+// Tests the Explain function gives the reason each variable file was, or was
+// not, claimed by a component.
+func Test_Explain(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		"orphan/dev.tfvars":              {},
+		"component/main.tf":              {},
+		"component/dev.tfvars":           {},
+		"component/vars/stg.tfvars":      {},
+		"component/vars/deep/prd.tfvars": {},
+		"component/nested/main.tf":       {},
+		"component/nested/uat.tfvars":    {},
+	}
+
+	want := []Claim{
+		{
+			VarFile:     "component/dev.tfvars",
+			Component:   "component",
+			Environment: "dev",
+			Reason:      "component in the same directory",
+		},
+		{
+			VarFile:     "component/nested/uat.tfvars",
+			Component:   "component/nested",
+			Environment: "uat",
+			Reason:      "component in the same directory",
+		},
+		{
+			VarFile: "component/vars/deep/prd.tfvars",
+			Reason:  "nearest component component is 2 directories up, exceeding the max depth of 1",
+		},
+		{
+			VarFile:     "component/vars/stg.tfvars",
+			Component:   "component",
+			Environment: "stg",
+			Reason:      "nearest component is 1 directory up",
+		},
+		{
+			VarFile: "orphan/dev.tfvars",
+			Reason:  "no component in its directory or any parent directory",
+		},
+	}
+
+	got, err := Explain(context.Background(), fsys, Options{MaxVarFileDepth: 1})
+	if err != nil {
+		t.Fatalf("Explain() error: %s", err)
+	}
+
+	if !cmp.Equal(got, want) {
+		t.Errorf(`Explain()
+		diff %s`, cmp.Diff(got, want))
+	}
+}
+
+// Tests the forEach function visits every index once and returns the first
+// error.
+func Test_ForEach(t *testing.T) {
+	t.Parallel()
+
+	visited := make([]int, 100)
+	err := forEach(context.Background(), 4, len(visited), func(i int) error {
+		visited[i]++
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("forEach() error: %s", err)
+	}
+	for i, n := range visited {
+		if n != 1 {
+			t.Errorf("forEach() visited %d %d times", i, n)
+		}
+	}
+
+	want := fmt.Errorf("failed")
+	err = forEach(context.Background(), 4, len(visited), func(i int) error {
+		return want
+	})
+	if !errors.Is(err, want) {
+		t.Errorf("forEach() error = %v, want %v", err, want)
+	}
+}
+
+// syntheticTree generates a directory of groups of components, each with var
+// files for a few environments, nested var files and a nested module.
+func syntheticTree(b *testing.B, groups, components int) string {
+	root := b.TempDir()
+	for g := 0; g < groups; g++ {
+		for c := 0; c < components; c++ {
+			dir := filepath.Join(root, fmt.Sprintf("group%d", g), fmt.Sprintf("component%d", c))
+			files := []string{
+				"main.tf",
+				"variables.tf",
+				"backend/dev.hcl",
+				"modules/network/main.tf",
+			}
+			for _, env := range []string{"dev", "stg", "prd"} {
+				files = append(files, env+".tfvars", "vars/regional/"+env+"-eu.tfvars")
+			}
+
+			for _, f := range files {
+				p := filepath.Join(dir, f)
+				if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+					b.Fatal(err)
+				}
+				if err := os.WriteFile(p, nil, 0o644); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	}
+	return root
+}
+
+// Benchmarks the Discover function on a tree of 2000 components spread over
+// 12000 directories.
+func BenchmarkDiscover(b *testing.B) {
+	fsys := os.DirFS(syntheticTree(b, 40, 50))
+	opts := Options{BackendConfigPattern: "backend/{env}.hcl"}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := Discover(context.Background(), fsys, opts); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
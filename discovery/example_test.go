@@ -0,0 +1,76 @@
+package discovery_test
+
+import (
+	"context"
+	"fmt"
+	"testing/fstest"
+
+	"github.com/3bbbeau/tfvars-atlantis-config/discovery"
+	"github.com/3bbbeau/tfvars-atlantis-config/repocfg"
+)
+
+func ExampleDiscover() {
+	fsys := fstest.MapFS{
+		"network/main.tf":     {},
+		"network/dev.tfvars":  {},
+		"network/prod.tfvars": {},
+	}
+
+	components, err := discovery.Discover(context.Background(), fsys, discovery.Options{})
+	if err != nil {
+		panic(err)
+	}
+
+	for _, c := range components {
+		for _, v := range c.VarFiles {
+			fmt.Println(c.Path, v.Path, v.Environment)
+		}
+	}
+	// Output:
+	// network network/dev.tfvars dev
+	// network network/prod.tfvars prod
+}
+
+func ExampleDiscover_directoryStrategy() {
+	fsys := fstest.MapFS{
+		"network/main.tf":                    {},
+		"network/envs/dev/terraform.tfvars":  {},
+		"network/envs/prod/terraform.tfvars": {},
+	}
+
+	components, err := discovery.Discover(context.Background(), fsys, discovery.Options{
+		Strategy: discovery.DirectoryStrategy{},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	for _, c := range components {
+		for _, v := range c.VarFiles {
+			fmt.Println(c.Path, v.Path, v.Environment)
+		}
+	}
+	// Output:
+	// network network/envs/dev/terraform.tfvars dev
+	// network network/envs/prod/terraform.tfvars prod
+}
+
+func ExampleGenerate() {
+	fsys := fstest.MapFS{
+		"network/main.tf":    {},
+		"network/dev.tfvars": {},
+	}
+
+	cfg, err := discovery.Generate(context.Background(), fsys, discovery.Options{
+		RepoCfg: repocfg.Options{UseWorkspaces: true},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	for _, p := range cfg.Projects {
+		fmt.Println(*p.Name, *p.Dir, *p.Workspace)
+	}
+	// Output:
+	// network-dev network dev
+}
@@ -0,0 +1,154 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+)
+
+// index is built from a single walk of the file system, recording the
+// directories containing Terraform files and all variable files, so that
+// ownership can be resolved without reading the file system again.
+type index struct {
+	// components are the sorted directories containing a Terraform file
+	components []string
+	// isComponent is the set of components
+	isComponent map[string]bool
+	// extensions are the Terraform file extensions found in each component,
+	// in the order of TERRAFORM_EXTS
+	extensions map[string][]string
+	// varFiles are the variable files in walk order
+	varFiles []string
+}
+
+// newIndex walks the file system once from the root directory and indexes it.
+func newIndex(ctx context.Context, fsys fs.FS, root string) (*index, error) {
+	idx := &index{
+		components:  []string{},
+		isComponent: map[string]bool{},
+		extensions:  map[string][]string{},
+		varFiles:    []string{},
+	}
+
+	err := fs.WalkDir(fsys, root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		switch {
+		case isTerraformFile(d.Name()):
+			// Each component should be a path containing a Terraform file
+			dir := path.Dir(p)
+			if !idx.isComponent[dir] {
+				idx.isComponent[dir] = true
+				idx.components = append(idx.components, dir)
+			}
+			if ext := terraformExt(d.Name()); !slices.Contains(idx.extensions[dir], ext) {
+				idx.extensions[dir] = append(idx.extensions[dir], ext)
+			}
+		case isVarFile(d.Name()):
+			idx.varFiles = append(idx.varFiles, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Directories are walked in lexical order, but a directory's files may
+	// sort after its subdirectories.
+	slices.Sort(idx.components)
+	for _, exts := range idx.extensions {
+		slices.SortFunc(exts, func(a, b string) int {
+			return slices.Index(TERRAFORM_EXTS, a) - slices.Index(TERRAFORM_EXTS, b)
+		})
+	}
+
+	return idx, nil
+}
+
+// claims resolves the ownership of every indexed variable file.
+func (idx *index) claims(strategy Strategy, maxDepth int) []Claim {
+	claims := make([]Claim, 0, len(idx.varFiles))
+	for _, v := range idx.varFiles {
+		claims = append(claims, claim(idx.isComponent, strategy, maxDepth, v))
+	}
+	return claims
+}
+
+// claim applies the ownership rules to a variable file:
+//
+//  1. The variable file belongs to the nearest directory at or above it
+//     that contains a Terraform file.
+//  2. If that directory is more than maxDepth directories above it, the
+//     variable file is not claimed. A maxDepth of 0 means no limit.
+//  3. If the Strategy gives the variable file no environment, it is not
+//     claimed.
+func claim(isComponent map[string]bool, strategy Strategy, maxDepth int, varFile string) Claim {
+	c := Claim{VarFile: varFile}
+
+	dir := path.Dir(varFile)
+	depth := 0
+	for !isComponent[dir] {
+		if dir == "." {
+			c.Reason = "no component in its directory or any parent directory"
+			return c
+		}
+		dir = path.Dir(dir)
+		depth++
+	}
+
+	if maxDepth > 0 && depth > maxDepth {
+		c.Reason = fmt.Sprintf("nearest component %s is %d directories up, exceeding the max depth of %d", dir, depth, maxDepth)
+		return c
+	}
+
+	env, ok := strategy.Environment(relativeVarFile(dir, varFile))
+	if !ok {
+		c.Reason = fmt.Sprintf("no environment for it under component %s", dir)
+		return c
+	}
+
+	c.Component = dir
+	c.Environment = env
+	switch depth {
+	case 0:
+		c.Reason = "component in the same directory"
+	case 1:
+		c.Reason = "nearest component is 1 directory up"
+	default:
+		c.Reason = fmt.Sprintf("nearest component is %d directories up", depth)
+	}
+	return c
+}
+
+// isTerraformFile returns true if the file name is a Terraform or OpenTofu
+// configuration file
+func isTerraformFile(name string) bool {
+	return terraformExt(name) != ""
+}
+
+// terraformExt returns the extension of a Terraform or OpenTofu configuration
+// file, or an empty string if it is not one.
+func terraformExt(name string) string {
+	for _, ext := range TERRAFORM_EXTS {
+		if strings.HasSuffix(name, ext) {
+			return ext
+		}
+	}
+	return ""
+}
+
+// isVarFile returns true if the file name is a Terraform variable file
+func isVarFile(name string) bool {
+	return strings.HasSuffix(name, TFVARS_EXT) || strings.HasSuffix(name, TFVARS_JSON_EXT)
+}
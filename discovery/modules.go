@@ -0,0 +1,116 @@
+package discovery
+
+import (
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/zclconf/go-cty/cty"
+	"go.uber.org/zap"
+)
+
+// moduleSchema is the subset of the Terraform configuration schema used to
+// find the modules called by a component
+var (
+	moduleSchema = &hcl.BodySchema{
+		Blocks: []hcl.BlockHeaderSchema{
+			{Type: "module", LabelNames: []string{"name"}},
+		},
+	}
+	moduleCallSchema = &hcl.BodySchema{
+		Attributes: []hcl.AttributeSchema{
+			{Name: "source"},
+		},
+	}
+)
+
+// discoverModules finds the local modules called by the configuration files
+// directly within a directory, returning their directories relative to the
+// root of fsys, sorted.
+//
+// Only local sources, starting with "./" or "../", are resolved. Modules
+// outside the root of fsys, and files that cannot be parsed, are logged and
+// skipped, as Terraform will report them when it runs.
+func discoverModules(fsys fs.FS, logger *zap.Logger, dir string) ([]string, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	parser := hclparse.NewParser()
+	var modules []string
+
+	for _, entry := range entries {
+		name := path.Join(dir, entry.Name())
+		if entry.IsDir() || !isTerraformFile(entry.Name()) {
+			continue
+		}
+
+		src, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+
+		var file *hcl.File
+		var diags hcl.Diagnostics
+		if strings.HasSuffix(name, ".json") {
+			file, diags = parser.ParseJSON(src, name)
+		} else {
+			file, diags = parser.ParseHCL(src, name)
+		}
+		if diags.HasErrors() {
+			logger.Debug("ignoring modules of unparsable file", zap.String("file", name), zap.Error(diags))
+			continue
+		}
+
+		content, _, _ := file.Body.PartialContent(moduleSchema)
+		for _, block := range content.Blocks {
+			source, ok := moduleSource(block)
+			if !ok || !isLocalSource(source) {
+				continue
+			}
+
+			module := path.Join(dir, source)
+			if module == ".." || strings.HasPrefix(module, "../") {
+				logger.Debug("ignoring module outside the root", zap.String("file", name), zap.String("source", source))
+				continue
+			}
+
+			logger.Debug("calls local module", zap.String("module", module))
+			if !slices.Contains(modules, module) {
+				modules = append(modules, module)
+			}
+		}
+	}
+
+	slices.Sort(modules)
+	return modules, nil
+}
+
+// moduleSource returns the literal source of a module block, if it has one.
+func moduleSource(block *hcl.Block) (string, bool) {
+	attrs, _, diags := block.Body.PartialContent(moduleCallSchema)
+	if diags.HasErrors() {
+		return "", false
+	}
+
+	attr, ok := attrs.Attributes["source"]
+	if !ok {
+		return "", false
+	}
+
+	val, diags := attr.Expr.Value(nil)
+	if diags.HasErrors() || val.IsNull() || !val.IsKnown() || val.Type() != cty.String {
+		return "", false
+	}
+	return val.AsString(), true
+}
+
+// isLocalSource returns true if the module source is a local path, as
+// opposed to a registry or remote source.
+func isLocalSource(source string) bool {
+	return strings.HasPrefix(source, "./") || strings.HasPrefix(source, "../")
+}
@@ -0,0 +1,50 @@
+package discovery
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/google/go-cmp/cmp"
+	"go.uber.org/zap"
+)
+
+// Tests the discoverModules function only resolves local module sources
+// within the root.
+func Test_DiscoverModules(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		"network/main.tf": {Data: []byte(`
+module "vpc" {
+  source = "../modules/vpc"
+}
+
+module "subnets" {
+  source = "./subnets"
+}
+
+module "registry" {
+  source = "terraform-aws-modules/vpc/aws"
+}
+
+module "outside" {
+  source = "../../shared/vpc"
+}
+`)},
+		"network/dns.tf.json": {Data: []byte(`{"module": {"dns": {"source": "../modules/dns"}, "vpc": {"source": "../modules/vpc"}}}`)},
+		"network/broken.tf":   {Data: []byte(`module "broken" {`)},
+		"network/dev.tfvars":  {Data: []byte(`source = "../modules/ignored"`)},
+	}
+
+	want := []string{"modules/dns", "modules/vpc", "network/subnets"}
+
+	got, err := discoverModules(fsys, zap.NewNop(), "network")
+	if err != nil {
+		t.Fatalf("discoverModules() error: %s", err)
+	}
+
+	if !cmp.Equal(got, want) {
+		t.Errorf(`discoverModules()
+		diff %s`, cmp.Diff(got, want))
+	}
+}
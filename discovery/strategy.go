@@ -0,0 +1,122 @@
+package discovery
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+const (
+	FILENAME_STRATEGY  = "filename"
+	DIRECTORY_STRATEGY = "directory"
+	REGEX_STRATEGY     = "regex"
+
+	// ENV_REGEX_GROUP is the named capture group used by the regex strategy
+	// to extract the environment name.
+	ENV_REGEX_GROUP = "env"
+)
+
+// Strategy determines the environment name of a Terraform variable file
+// discovered for a Terraform component.
+type Strategy interface {
+	// Environment returns the environment name of the variable file, given
+	// its slash separated path relative to the component. If the variable
+	// file does not belong to any environment, false is returned and it is
+	// ignored.
+	Environment(varFile string) (string, bool)
+}
+
+// FilenameStrategy names environments after the variable file names, e.g.
+//
+//	dev.tfvars -> dev
+//	vars/stg.tfvars.json -> stg
+type FilenameStrategy struct{}
+
+// Environment implements Strategy
+func (FilenameStrategy) Environment(varFile string) (string, bool) {
+	name := path.Base(varFile)
+	for _, ext := range []string{TFVARS_JSON_EXT, TFVARS_EXT} {
+		if strings.HasSuffix(name, ext) {
+			return strings.TrimSuffix(name, ext), true
+		}
+	}
+	return "", false
+}
+
+// DirectoryStrategy names environments after the directory containing the
+// variable files, e.g.
+//
+//	envs/dev/terraform.tfvars -> dev
+//	envs/prd/terraform.tfvars -> prd
+//
+// Variable files at the root of the component have no environment.
+type DirectoryStrategy struct{}
+
+// Environment implements Strategy
+func (DirectoryStrategy) Environment(varFile string) (string, bool) {
+	dir := path.Dir(varFile)
+	if dir == "." {
+		return "", false
+	}
+	return path.Base(dir), true
+}
+
+// RegexStrategy names environments after the `env` named capture group, or
+// otherwise the first capture group, of a regular expression matched against
+// the variable file path, e.g. with `^config/(?P<env>[^/]+)\.auto\.tfvars$`
+//
+//	config/dev.auto.tfvars -> dev
+//
+// Variable files not matching the expression have no environment.
+type RegexStrategy struct {
+	Regexp *regexp.Regexp
+}
+
+// Environment implements Strategy
+func (s RegexStrategy) Environment(varFile string) (string, bool) {
+	matches := s.Regexp.FindStringSubmatch(varFile)
+	if matches == nil {
+		return "", false
+	}
+
+	group := 1
+	if idx := s.Regexp.SubexpIndex(ENV_REGEX_GROUP); idx > 0 {
+		group = idx
+	}
+	if matches[group] == "" {
+		return "", false
+	}
+	return matches[group], true
+}
+
+// NewStrategy returns the Strategy selected by name. The expression is only
+// used by the regex strategy.
+func NewStrategy(name, expr string) (Strategy, error) {
+	switch name {
+	case "", FILENAME_STRATEGY:
+		return FilenameStrategy{}, nil
+	case DIRECTORY_STRATEGY:
+		return DirectoryStrategy{}, nil
+	case REGEX_STRATEGY:
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("env regex: %w", err)
+		}
+		if re.NumSubexp() == 0 {
+			return nil, fmt.Errorf("env regex %q must contain a capture group", expr)
+		}
+		return RegexStrategy{Regexp: re}, nil
+	default:
+		return nil, fmt.Errorf("unknown env strategy %q, must be one of: %s, %s, %s", name, FILENAME_STRATEGY, DIRECTORY_STRATEGY, REGEX_STRATEGY)
+	}
+}
+
+// relativeVarFile returns the path of a variable file relative to its
+// component, as passed to a Strategy.
+func relativeVarFile(component, varFile string) string {
+	if component == "." {
+		return varFile
+	}
+	return strings.TrimPrefix(varFile, component+"/")
+}
@@ -0,0 +1,100 @@
+package discovery
+
+import (
+	"testing"
+)
+
+// Tests each Strategy derives the environment name of a variable file
+// relative to its component.
+func Test_Strategy(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		strategy string
+		expr     string
+		varFile  string
+		want     string
+		wantOk   bool
+	}{
+		{
+			name:     "Filename",
+			strategy: FILENAME_STRATEGY,
+			varFile:  "dev.tfvars",
+			want:     "dev",
+			wantOk:   true,
+		},
+		{
+			name:     "FilenameJSON",
+			strategy: FILENAME_STRATEGY,
+			varFile:  "vars/stg.tfvars.json",
+			want:     "stg",
+			wantOk:   true,
+		},
+		{
+			name:     "Directory",
+			strategy: DIRECTORY_STRATEGY,
+			varFile:  "envs/prd/terraform.tfvars",
+			want:     "prd",
+			wantOk:   true,
+		},
+		{
+			name:     "DirectoryAtComponentRoot",
+			strategy: DIRECTORY_STRATEGY,
+			varFile:  "terraform.tfvars",
+			wantOk:   false,
+		},
+		{
+			name:     "RegexNamedGroup",
+			strategy: REGEX_STRATEGY,
+			expr:     `^(config)/(?P<env>[^/]+)\.auto\.tfvars$`,
+			varFile:  "config/dev.auto.tfvars",
+			want:     "dev",
+			wantOk:   true,
+		},
+		{
+			name:     "RegexFirstGroup",
+			strategy: REGEX_STRATEGY,
+			expr:     `^([^/.]+)-vars\.tfvars$`,
+			varFile:  "uat-vars.tfvars",
+			want:     "uat",
+			wantOk:   true,
+		},
+		{
+			name:     "RegexNoMatch",
+			strategy: REGEX_STRATEGY,
+			expr:     `^envs/([^/]+)/`,
+			varFile:  "dev.tfvars",
+			wantOk:   false,
+		},
+	}
+
+	for _, tc := range tests {
+		strategy, err := NewStrategy(tc.strategy, tc.expr)
+		if err != nil {
+			t.Fatalf("%s: NewStrategy() error: %s", tc.name, err)
+		}
+
+		got, ok := strategy.Environment(tc.varFile)
+		if got != tc.want || ok != tc.wantOk {
+			t.Errorf(`%s: Environment()
+			got %q, %v
+			want %q, %v`, tc.name, got, ok, tc.want, tc.wantOk)
+		}
+	}
+}
+
+// Tests NewStrategy rejects unknown strategies and unusable expressions.
+func Test_NewStrategyErrors(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct{ strategy, expr string }{
+		{strategy: "workspace"},
+		{strategy: REGEX_STRATEGY, expr: `envs/[^/]+`},
+		{strategy: REGEX_STRATEGY, expr: `(`},
+	} {
+		if _, err := NewStrategy(tc.strategy, tc.expr); err == nil {
+			t.Errorf("NewStrategy(%q, %q) expected error", tc.strategy, tc.expr)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package discovery
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/hashicorp/go-version"
+	"go.uber.org/zap"
+)
+
+const (
+	// TERRAFORM_VERSION_FILE pins the Terraform version of the directory it is
+	// in and its subdirectories for tfenv.
+	TERRAFORM_VERSION_FILE = ".terraform-version"
+
+	// TOOL_VERSIONS_FILE pins the versions of tools, including Terraform, of
+	// the directory it is in and its subdirectories for asdf.
+	TOOL_VERSIONS_FILE = ".tool-versions"
+)
+
+// discoverTerraformVersion finds the Terraform version pinned for a component
+// by the nearest version file in its directory or a parent directory within
+// the file system, returning the version and the file it was read from, or
+// empty strings if there is none. In the same directory, TERRAFORM_VERSION_FILE
+// takes precedence over TOOL_VERSIONS_FILE.
+//
+// Only exact versions are used, as Atlantis does not resolve tfenv keywords
+// such as `latest`. If the nearest version file pins anything else, it is
+// ignored with a warning and no version is returned, so the component falls
+// back to the default rather than a version pinned further up.
+func discoverTerraformVersion(fsys fs.FS, logger *zap.Logger, dir string) (string, string, error) {
+	for {
+		for _, name := range []string{TERRAFORM_VERSION_FILE, TOOL_VERSIONS_FILE} {
+			p := path.Join(dir, name)
+			b, err := fs.ReadFile(fsys, p)
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			if err != nil {
+				return "", "", err
+			}
+
+			var v string
+			if name == TERRAFORM_VERSION_FILE {
+				v = parseTerraformVersionFile(b)
+			} else {
+				v = parseToolVersionsFile(b)
+			}
+
+			switch {
+			case v == "":
+				continue
+			case !isExactVersion(v):
+				logger.Warn("ignoring terraform version that is not an exact version", zap.String("file", p), zap.String("terraform_version", v))
+				return "", "", nil
+			}
+			return v, p, nil
+		}
+
+		if dir == "." {
+			return "", "", nil
+		}
+		dir = path.Dir(dir)
+	}
+}
+
+// parseTerraformVersionFile returns the version in a .terraform-version file,
+// its first line that is not blank or a comment.
+func parseTerraformVersionFile(b []byte) string {
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		return line
+	}
+	return ""
+}
+
+// parseToolVersionsFile returns the terraform version in a .tool-versions
+// file, the first of the versions on its `terraform` line, e.g.:
+//
+//	terraform 1.5.7 1.4.6 # fallback
+func parseToolVersionsFile(b []byte) string {
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fields[0] == "terraform" {
+			return fields[1]
+		}
+	}
+	return ""
+}
+
+// isExactVersion returns true if v is a single version rather than a
+// constraint or keyword, e.g. 1.5.7 or v1.6.0-beta1.
+func isExactVersion(v string) bool {
+	_, err := version.NewSemver(v)
+	return err == nil
+}
@@ -0,0 +1,61 @@
+package discovery
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/google/go-cmp/cmp"
+	"go.uber.org/zap"
+)
+
+// Tests the discoverTerraformVersion function finds the version pinned by the
+// nearest version file, skipping those without a terraform version, and none
+// if the nearest pins one that is not exact.
+func Test_DiscoverTerraformVersion(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		".tool-versions":                      {Data: []byte("nodejs 20.11.0\nterraform 1.5.7 1.4.6 # fallback\n")},
+		"aws/.terraform-version":              {Data: []byte("# pinned\nv1.8.0\n")},
+		"aws/.tool-versions":                  {Data: []byte("terraform 1.7.0\n")},
+		"aws/network/main.tf":                 {},
+		"aws/latest/.terraform-version":       {Data: []byte("latest:^1.9\n")},
+		"aws/latest/main.tf":                  {},
+		"gcp/.tool-versions":                  {Data: []byte("nodejs 20.11.0\n")},
+		"gcp/network/main.tf":                 {},
+		"azure/network/.tool-versions":        {Data: []byte("terraform 1.6.6\n")},
+		"azure/network/main.tf":               {},
+		"azure/network/nested/.tool-versions": {Data: []byte("terraform system\n")},
+	}
+
+	tests := []struct {
+		dir      string
+		want     string
+		wantFile string
+	}{
+		{dir: "aws/network", want: "v1.8.0", wantFile: "aws/.terraform-version"},
+		{dir: "aws/latest"},
+		{dir: "gcp/network", want: "1.5.7", wantFile: ".tool-versions"},
+		{dir: "azure/network", want: "1.6.6", wantFile: "azure/network/.tool-versions"},
+		{dir: "azure/network/nested"},
+		{dir: ".", want: "1.5.7", wantFile: ".tool-versions"},
+	}
+
+	for _, tc := range tests {
+		got, gotFile, err := discoverTerraformVersion(fsys, zap.NewNop(), tc.dir)
+		if err != nil {
+			t.Errorf("%s: discoverTerraformVersion() error: %s", tc.dir, err)
+			continue
+		}
+
+		if !cmp.Equal([]string{got, gotFile}, []string{tc.want, tc.wantFile}) {
+			t.Errorf(`%s: discoverTerraformVersion()
+			diff %s`, tc.dir, cmp.Diff([]string{got, gotFile}, []string{tc.want, tc.wantFile}))
+		}
+	}
+
+	got, gotFile, err := discoverTerraformVersion(fstest.MapFS{"network/main.tf": {}}, zap.NewNop(), "network")
+	if err != nil || got != "" || gotFile != "" {
+		t.Errorf("discoverTerraformVersion() = %q, %q, %v, want no version", got, gotFile, err)
+	}
+}
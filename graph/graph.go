@@ -0,0 +1,209 @@
+// Package graph builds the dependency graph of discovered Terraform
+// components, the local modules they call and their variable files, and
+// renders it as DOT or Mermaid.
+package graph
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/3bbbeau/tfvars-atlantis-config/repocfg"
+)
+
+// Kind is the kind of a Node
+type Kind string
+
+const (
+	// COMPONENT_NODE is a Terraform component Atlantis plans
+	COMPONENT_NODE Kind = "component"
+	// MODULE_NODE is a local module called by a component, without any
+	// variable files of its own
+	MODULE_NODE Kind = "module"
+	// VAR_FILE_NODE is the variable file of a component's environment
+	VAR_FILE_NODE Kind = "var_file"
+)
+
+// Node represents a component, module or variable file, identified by its
+// path relative to the repo root.
+type Node struct {
+	ID   string
+	Kind Kind
+}
+
+// Edge represents a component or module calling a module, or a component
+// having a variable file, labelled with its environment.
+type Edge struct {
+	From  string
+	To    string
+	Label string
+}
+
+// Graph represents the dependencies between components, modules and
+// variable files.
+type Graph struct {
+	Nodes []Node
+	Edges []Edge
+}
+
+// Options filters the graph to part of the discovered components.
+type Options struct {
+	// Component limits the graph to the component with this path, and the
+	// modules it calls directly or through other modules.
+	Component string
+
+	// Environment limits the graph to the variable files of this
+	// environment, and the components having one with the modules they call.
+	Environment string
+}
+
+// New builds the graph of the components, in the order of the components
+// and their variable files and modules.
+func New(components []repocfg.Component, opts Options) (*Graph, error) {
+	byPath := map[string]repocfg.Component{}
+	called := map[string]bool{}
+	for _, c := range components {
+		byPath[c.Path] = c
+		for _, m := range c.Modules {
+			called[m] = true
+		}
+	}
+
+	if _, ok := byPath[opts.Component]; opts.Component != "" && !ok {
+		return nil, fmt.Errorf("no component %s", opts.Component)
+	}
+
+	// The roots of the graph are the components matching the options, the
+	// modules they call are added as they are reached.
+	var roots []string
+	for _, c := range components {
+		if opts.Component != "" && c.Path != opts.Component {
+			continue
+		}
+		if opts.Environment != "" && !slices.ContainsFunc(c.VarFiles, func(v repocfg.VarFile) bool { return v.Environment == opts.Environment }) {
+			continue
+		}
+		roots = append(roots, c.Path)
+	}
+
+	g := &Graph{}
+	seen := map[string]bool{}
+	queue := roots
+	for len(queue) > 0 {
+		dir := queue[0]
+		queue = queue[1:]
+		if seen[dir] {
+			continue
+		}
+		seen[dir] = true
+
+		c := byPath[dir]
+		kind := COMPONENT_NODE
+		if called[dir] && len(c.VarFiles) == 0 {
+			kind = MODULE_NODE
+		}
+		g.Nodes = append(g.Nodes, Node{ID: dir, Kind: kind})
+
+		for _, m := range c.Modules {
+			g.Edges = append(g.Edges, Edge{From: dir, To: m})
+			queue = append(queue, m)
+		}
+
+		for _, v := range c.VarFiles {
+			if opts.Environment != "" && v.Environment != opts.Environment {
+				continue
+			}
+			g.Nodes = append(g.Nodes, Node{ID: v.Path, Kind: VAR_FILE_NODE})
+			g.Edges = append(g.Edges, Edge{From: dir, To: v.Path, Label: v.Environment})
+		}
+	}
+
+	return g, nil
+}
+
+// dotShapes are the DOT shapes of each kind of node
+var dotShapes = map[Kind]string{
+	COMPONENT_NODE: "box",
+	MODULE_NODE:    "component",
+	VAR_FILE_NODE:  "note",
+}
+
+// WriteDOT writes the graph in the Graphviz DOT language, e.g.:
+//
+//	digraph {
+//	  rankdir=LR;
+//	  "network" [shape=box];
+//	  "modules/vpc" [shape=component];
+//	  "network/dev.tfvars" [shape=note];
+//	  "network" -> "modules/vpc";
+//	  "network" -> "network/dev.tfvars" [label="dev"];
+//	}
+func (g *Graph) WriteDOT(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("digraph {\n  rankdir=LR;\n")
+	for _, n := range g.Nodes {
+		fmt.Fprintf(&b, "  %q [shape=%s];\n", n.ID, dotShapes[n.Kind])
+	}
+	for _, e := range g.Edges {
+		if e.Label != "" {
+			fmt.Fprintf(&b, "  %q -> %q [label=%q];\n", e.From, e.To, e.Label)
+		} else {
+			fmt.Fprintf(&b, "  %q -> %q;\n", e.From, e.To)
+		}
+	}
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// mermaidShapes are the opening and closing brackets of the Mermaid shapes
+// of each kind of node
+var mermaidShapes = map[Kind][2]string{
+	COMPONENT_NODE: {"[", "]"},
+	MODULE_NODE:    {"[[", "]]"},
+	VAR_FILE_NODE:  {">", "]"},
+}
+
+// WriteMermaid writes the graph as a Mermaid flowchart, e.g.:
+//
+//	flowchart LR
+//	  n0["network"]
+//	  n1[["modules/vpc"]]
+//	  n2>"network/dev.tfvars"]
+//	  n0 --> n1
+//	  n0 -->|"dev"| n2
+//
+// Node IDs are generated, as paths are not valid Mermaid IDs.
+func (g *Graph) WriteMermaid(w io.Writer) error {
+	ids := map[string]string{}
+	id := func(node string) string {
+		if _, ok := ids[node]; !ok {
+			ids[node] = fmt.Sprintf("n%d", len(ids))
+		}
+		return ids[node]
+	}
+
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	for _, n := range g.Nodes {
+		shape := mermaidShapes[n.Kind]
+		fmt.Fprintf(&b, "  %s%s%s%s\n", id(n.ID), shape[0], mermaidText(n.ID), shape[1])
+	}
+	for _, e := range g.Edges {
+		if e.Label != "" {
+			fmt.Fprintf(&b, "  %s -->|%s| %s\n", id(e.From), mermaidText(e.Label), id(e.To))
+		} else {
+			fmt.Fprintf(&b, "  %s --> %s\n", id(e.From), id(e.To))
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// mermaidText quotes text for Mermaid, escaping any quotes within it
+func mermaidText(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, "#quot;") + `"`
+}
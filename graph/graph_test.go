@@ -0,0 +1,171 @@
+package graph
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/3bbbeau/tfvars-atlantis-config/repocfg"
+	"github.com/google/go-cmp/cmp"
+)
+
+var components = []repocfg.Component{
+	{
+		Path:     "db",
+		VarFiles: []repocfg.VarFile{{Path: "db/dev.tfvars", Environment: "dev"}, {Path: "db/prd.tfvars", Environment: "prd"}},
+	},
+	{
+		Path:    "modules/vpc",
+		Modules: []string{"modules/subnets"},
+	},
+	{
+		Path: "modules/subnets",
+	},
+	{
+		Path:     "network",
+		VarFiles: []repocfg.VarFile{{Path: "network/dev.tfvars", Environment: "dev"}},
+		Modules:  []string{"modules/vpc"},
+	},
+}
+
+// Tests the New function builds the graph of the components matching the
+// options, and the modules they call.
+func Test_New(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		options Options
+		want    *Graph
+	}{
+		{
+			name: "All",
+			want: &Graph{
+				Nodes: []Node{
+					{ID: "db", Kind: COMPONENT_NODE},
+					{ID: "db/dev.tfvars", Kind: VAR_FILE_NODE},
+					{ID: "db/prd.tfvars", Kind: VAR_FILE_NODE},
+					{ID: "modules/vpc", Kind: MODULE_NODE},
+					{ID: "modules/subnets", Kind: MODULE_NODE},
+					{ID: "network", Kind: COMPONENT_NODE},
+					{ID: "network/dev.tfvars", Kind: VAR_FILE_NODE},
+				},
+				Edges: []Edge{
+					{From: "db", To: "db/dev.tfvars", Label: "dev"},
+					{From: "db", To: "db/prd.tfvars", Label: "prd"},
+					{From: "modules/vpc", To: "modules/subnets"},
+					{From: "network", To: "modules/vpc"},
+					{From: "network", To: "network/dev.tfvars", Label: "dev"},
+				},
+			},
+		},
+		{
+			name:    "Component",
+			options: Options{Component: "network"},
+			want: &Graph{
+				Nodes: []Node{
+					{ID: "network", Kind: COMPONENT_NODE},
+					{ID: "network/dev.tfvars", Kind: VAR_FILE_NODE},
+					{ID: "modules/vpc", Kind: MODULE_NODE},
+					{ID: "modules/subnets", Kind: MODULE_NODE},
+				},
+				Edges: []Edge{
+					{From: "network", To: "modules/vpc"},
+					{From: "network", To: "network/dev.tfvars", Label: "dev"},
+					{From: "modules/vpc", To: "modules/subnets"},
+				},
+			},
+		},
+		{
+			name:    "Environment",
+			options: Options{Environment: "prd"},
+			want: &Graph{
+				Nodes: []Node{
+					{ID: "db", Kind: COMPONENT_NODE},
+					{ID: "db/prd.tfvars", Kind: VAR_FILE_NODE},
+				},
+				Edges: []Edge{
+					{From: "db", To: "db/prd.tfvars", Label: "prd"},
+				},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := New(components, tc.options)
+			if err != nil {
+				t.Fatalf("New() error: %s", err)
+			}
+
+			if !cmp.Equal(got, tc.want) {
+				t.Errorf(`New()
+				diff %s`, cmp.Diff(got, tc.want))
+			}
+		})
+	}
+
+	if _, err := New(components, Options{Component: "missing"}); err == nil {
+		t.Errorf("New() expected error for unknown component")
+	}
+}
+
+// Tests the WriteDOT and WriteMermaid methods render each kind of node and
+// edge.
+func Test_Write(t *testing.T) {
+	t.Parallel()
+
+	g, err := New(components, Options{Component: "network"})
+	if err != nil {
+		t.Fatalf("New() error: %s", err)
+	}
+
+	tests := []struct {
+		name  string
+		write func(*Graph, *bytes.Buffer) error
+		want  string
+	}{
+		{
+			name:  "DOT",
+			write: func(g *Graph, b *bytes.Buffer) error { return g.WriteDOT(b) },
+			want: `digraph {
+  rankdir=LR;
+  "network" [shape=box];
+  "network/dev.tfvars" [shape=note];
+  "modules/vpc" [shape=component];
+  "modules/subnets" [shape=component];
+  "network" -> "modules/vpc";
+  "network" -> "network/dev.tfvars" [label="dev"];
+  "modules/vpc" -> "modules/subnets";
+}
+`,
+		},
+		{
+			name:  "Mermaid",
+			write: func(g *Graph, b *bytes.Buffer) error { return g.WriteMermaid(b) },
+			want: `flowchart LR
+  n0["network"]
+  n1>"network/dev.tfvars"]
+  n2[["modules/vpc"]]
+  n3[["modules/subnets"]]
+  n0 --> n2
+  n0 -->|"dev"| n1
+  n2 --> n3
+`,
+		},
+	}
+
+	for _, tc := range tests {
+		got := new(bytes.Buffer)
+		if err := tc.write(g, got); err != nil {
+			t.Errorf("%s error: %s", tc.name, err)
+		}
+
+		if got.String() != tc.want {
+			t.Errorf(`%s
+			diff %s`, tc.name, cmp.Diff(got.String(), tc.want))
+		}
+	}
+}
@@ -0,0 +1,280 @@
+// Package lint checks the Terraform variable files of discovered components
+// against the variables their components declare, before Atlantis plans them.
+package lint
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/3bbbeau/tfvars-atlantis-config/repocfg"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/zclconf/go-cty/cty/convert"
+)
+
+// Rule identifies the check which produced a Finding
+type Rule string
+
+const (
+	// PARSE_RULE reports files that cannot be parsed
+	PARSE_RULE Rule = "parse"
+	// UNDECLARED_RULE reports values for variables the component does not declare
+	UNDECLARED_RULE Rule = "undeclared"
+	// REQUIRED_RULE reports variables without a default missing from an environment
+	REQUIRED_RULE Rule = "required"
+	// TYPE_RULE reports values that cannot be converted to a primitive variable type
+	TYPE_RULE Rule = "type"
+)
+
+// Finding represents a problem found in a Terraform variable file.
+type Finding struct {
+	File        string `json:"file"`
+	Line        int    `json:"line"`
+	Column      int    `json:"column"`
+	Rule        Rule   `json:"rule"`
+	Message     string `json:"message"`
+	Component   string `json:"component"`
+	Environment string `json:"environment,omitempty"`
+}
+
+// Stringer implementation for Finding, e.g.:
+//
+//	network/dev.tfvars:3:1: undeclared: variable "regoin" is not declared by component network
+func (f Finding) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s: %s", f.File, f.Line, f.Column, f.Rule, f.Message)
+}
+
+// at sets the position of the finding from a source range
+func (f Finding) at(r hcl.Range) Finding {
+	f.File = r.Filename
+	f.Line = r.Start.Line
+	f.Column = r.Start.Column
+	return f
+}
+
+// Lint checks the Terraform variable files of each component against the
+// variables declared by the component, reporting:
+//
+//   - values for variables that are not declared
+//   - variables without a default that an environment has no value for
+//   - values that cannot be converted to the primitive type of the variable
+//
+// Variable files Terraform loads automatically, terraform.tfvars and
+// *.auto.tfvars, are taken into account for every environment.
+//
+// The paths of the components and their variable files are relative to the
+// root of fsys.
+func Lint(fsys fs.FS, components []repocfg.Component) ([]Finding, error) {
+	findings := []Finding{}
+	parser := hclparse.NewParser()
+
+	for _, c := range components {
+		variables, diags := parseVariables(fsys, parser, c.Path)
+		findings = append(findings, diagFindings(c, "", diags)...)
+
+		autoLoaded, diags := parseAutoLoaded(fsys, parser, c.Path)
+		findings = append(findings, diagFindings(c, "", diags)...)
+
+		// Group the variable files by environment, to check the variables
+		// required by each environment across all of its files.
+		environments := []string{}
+		values := map[string]hcl.Attributes{}
+		files := map[string][]string{}
+		for _, v := range c.VarFiles {
+			if !slices.Contains(environments, v.Environment) {
+				environments = append(environments, v.Environment)
+			}
+			files[v.Environment] = append(files[v.Environment], v.Path)
+
+			attrs, diags := parseValues(fsys, parser, v.Path)
+			findings = append(findings, diagFindings(c, v.Environment, diags)...)
+			findings = append(findings, checkValues(c, v.Environment, variables, attrs)...)
+
+			if values[v.Environment] == nil {
+				values[v.Environment] = hcl.Attributes{}
+			}
+			for name, attr := range attrs {
+				values[v.Environment][name] = attr
+			}
+		}
+
+		for _, env := range environments {
+			// Environments made only of automatically loaded files are not
+			// real environments, the files apply to all of them.
+			if !slices.ContainsFunc(files[env], func(f string) bool { return !isAutoLoaded(c.Path, f) }) {
+				continue
+			}
+			findings = append(findings, checkRequired(c, env, files[env][0], variables, values[env], autoLoaded)...)
+		}
+	}
+
+	return findings, nil
+}
+
+// checkValues checks each value assigned in a variable file is declared, and
+// has the variable's type.
+func checkValues(c repocfg.Component, env string, variables map[string]Variable, attrs hcl.Attributes) []Finding {
+	findings := []Finding{}
+	base := Finding{Component: c.Path, Environment: env}
+
+	for _, attr := range sortedAttributes(attrs) {
+		v, ok := variables[attr.Name]
+		if !ok {
+			f := base.at(attr.NameRange)
+			f.Rule = UNDECLARED_RULE
+			f.Message = fmt.Sprintf("variable %q is not declared by component %s", attr.Name, c.Path)
+			findings = append(findings, f)
+			continue
+		}
+
+		if !v.Type.IsPrimitiveType() {
+			continue
+		}
+
+		// Values referring to anything cannot be evaluated, and are left for
+		// Terraform to report.
+		val, diags := attr.Expr.Value(nil)
+		if diags.HasErrors() || val.IsNull() {
+			continue
+		}
+
+		if _, err := convert.Convert(val, v.Type); err != nil {
+			f := base.at(attr.Expr.Range())
+			f.Rule = TYPE_RULE
+			f.Message = fmt.Sprintf("variable %q must be a %s, declared at %s:%d: %s", attr.Name, v.Type.FriendlyName(), v.Range.Filename, v.Range.Start.Line, err)
+			findings = append(findings, f)
+		}
+	}
+
+	return findings
+}
+
+// checkRequired checks an environment has a value for each required variable,
+// reporting missing variables at the first variable file of the environment.
+func checkRequired(c repocfg.Component, env, file string, variables map[string]Variable, values, autoLoaded hcl.Attributes) []Finding {
+	findings := []Finding{}
+
+	names := make([]string, 0, len(variables))
+	for name := range variables {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		v := variables[name]
+		if !v.Required {
+			continue
+		}
+		if _, ok := values[name]; ok {
+			continue
+		}
+		if _, ok := autoLoaded[name]; ok {
+			continue
+		}
+
+		findings = append(findings, Finding{
+			File:        file,
+			Line:        1,
+			Column:      1,
+			Rule:        REQUIRED_RULE,
+			Message:     fmt.Sprintf("environment %s has no value for required variable %q, declared at %s:%d", env, name, v.Range.Filename, v.Range.Start.Line),
+			Component:   c.Path,
+			Environment: env,
+		})
+	}
+
+	return findings
+}
+
+// parseValues parses the values assigned in a variable file
+func parseValues(fsys fs.FS, parser *hclparse.Parser, name string) (hcl.Attributes, hcl.Diagnostics) {
+	file, diags := parseFile(fsys, parser, name)
+	if file == nil {
+		return nil, diags
+	}
+
+	attrs, attrDiags := file.Body.JustAttributes()
+	return attrs, append(diags, attrDiags...)
+}
+
+// parseAutoLoaded parses the values of the variable files Terraform loads
+// automatically from a component directory
+func parseAutoLoaded(fsys fs.FS, parser *hclparse.Parser, dir string) (hcl.Attributes, hcl.Diagnostics) {
+	values := hcl.Attributes{}
+	var diags hcl.Diagnostics
+
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return values, diags
+	}
+
+	for _, entry := range entries {
+		name := path.Join(dir, entry.Name())
+		if entry.IsDir() || !isAutoLoaded(dir, name) {
+			continue
+		}
+
+		attrs, attrDiags := parseValues(fsys, parser, name)
+		diags = append(diags, attrDiags...)
+		for k, attr := range attrs {
+			values[k] = attr
+		}
+	}
+
+	return values, diags
+}
+
+// isAutoLoaded returns true if Terraform loads the variable file
+// automatically for the component.
+func isAutoLoaded(dir, name string) bool {
+	if path.Dir(name) != dir {
+		return false
+	}
+
+	base := path.Base(name)
+	switch {
+	case base == "terraform.tfvars", base == "terraform.tfvars.json":
+		return true
+	case strings.HasSuffix(base, ".auto.tfvars"), strings.HasSuffix(base, ".auto.tfvars.json"):
+		return true
+	}
+	return false
+}
+
+// diagFindings converts error diagnostics to findings
+func diagFindings(c repocfg.Component, env string, diags hcl.Diagnostics) []Finding {
+	findings := []Finding{}
+
+	for _, diag := range diags {
+		if diag.Severity != hcl.DiagError {
+			continue
+		}
+
+		f := Finding{Component: c.Path, Environment: env, Rule: PARSE_RULE}
+		if diag.Subject != nil {
+			f = f.at(*diag.Subject)
+		}
+		f.Message = diag.Summary
+		if diag.Detail != "" {
+			f.Message += ": " + diag.Detail
+		}
+		findings = append(findings, f)
+	}
+
+	return findings
+}
+
+// sortedAttributes returns the attributes in the order they are declared
+func sortedAttributes(attrs hcl.Attributes) []*hcl.Attribute {
+	sorted := make([]*hcl.Attribute, 0, len(attrs))
+	for _, attr := range attrs {
+		sorted = append(sorted, attr)
+	}
+	slices.SortFunc(sorted, func(a, b *hcl.Attribute) int {
+		return a.Range.Start.Byte - b.Range.Start.Byte
+	})
+	return sorted
+}
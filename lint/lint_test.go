@@ -0,0 +1,105 @@
+package lint
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/3bbbeau/tfvars-atlantis-config/repocfg"
+	"github.com/google/go-cmp/cmp"
+)
+
+// Tests the Lint function reports problems in var files with their position.
+func Test_Lint(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		"net/main.tf": {Data: []byte(`
+variable "region" {
+  type = string
+}
+
+variable "zones" {
+  type    = number
+  default = 2
+}
+
+variable "tags" {
+  type    = map(string)
+  default = {}
+}
+`)},
+		"net/variables.tf.json": {Data: []byte(`{"variable": {"enabled": {"type": "bool"}}}`)},
+		"net/terraform.tfvars":  {Data: []byte(`enabled = true`)},
+		"net/dev.tfvars": {Data: []byte(`region = "eu-west-1"
+zones  = "three"
+regoin = "eu-west-2"
+tags   = { team = "net" }
+`)},
+		// The directives of the project are ignored by Terraform and lint
+		"net/prd.tfvars.json": {Data: []byte(`{"zones": "3", "enabled": "maybe", "//": ["atlantis:autoplan=false"]}`)},
+		"net/stg.tfvars":      {Data: []byte(`region = `)},
+	}
+
+	components := []repocfg.Component{
+		{
+			Path: "net",
+			VarFiles: []repocfg.VarFile{
+				{Path: "net/dev.tfvars", Environment: "dev"},
+				{Path: "net/prd.tfvars.json", Environment: "prd"},
+				{Path: "net/stg.tfvars", Environment: "stg"},
+				{Path: "net/terraform.tfvars", Environment: "terraform"},
+			},
+		},
+	}
+
+	want := []Finding{
+		{
+			File: "net/dev.tfvars", Line: 2, Column: 10, Rule: TYPE_RULE,
+			Message:   `variable "zones" must be a number, declared at net/main.tf:6: a number is required`,
+			Component: "net", Environment: "dev",
+		},
+		{
+			File: "net/dev.tfvars", Line: 3, Column: 1, Rule: UNDECLARED_RULE,
+			Message:   `variable "regoin" is not declared by component net`,
+			Component: "net", Environment: "dev",
+		},
+		{
+			File: "net/prd.tfvars.json", Line: 1, Column: 27, Rule: TYPE_RULE,
+			Message:   `variable "enabled" must be a bool, declared at net/variables.tf.json:1: a bool is required`,
+			Component: "net", Environment: "prd",
+		},
+		{
+			File: "net/stg.tfvars", Line: 1, Column: 10, Rule: PARSE_RULE,
+			Message:   "Missing expression: Expected the start of an expression, but found the end of the file.",
+			Component: "net", Environment: "stg",
+		},
+		{
+			File: "net/prd.tfvars.json", Line: 1, Column: 1, Rule: REQUIRED_RULE,
+			Message:   `environment prd has no value for required variable "region", declared at net/main.tf:2`,
+			Component: "net", Environment: "prd",
+		},
+	}
+
+	got, err := Lint(fsys, components)
+	if err != nil {
+		t.Fatalf("Lint() error: %s", err)
+	}
+
+	if !cmp.Equal(got, want) {
+		t.Errorf(`Lint()
+		diff %s`, cmp.Diff(got, want))
+	}
+}
+
+// Tests the String method of a finding
+func Test_FindingString(t *testing.T) {
+	t.Parallel()
+
+	f := Finding{File: "net/dev.tfvars", Line: 3, Column: 1, Rule: UNDECLARED_RULE, Message: "msg"}
+	want := "net/dev.tfvars:3:1: undeclared: msg"
+	if got := f.String(); got != want {
+		t.Errorf(`String()
+		got %s
+		want %s`, got, want)
+	}
+}
@@ -0,0 +1,142 @@
+package lint
+
+import (
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/3bbbeau/tfvars-atlantis-config/discovery"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/ext/typeexpr"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Variable represents a `variable` block declared by a Terraform component.
+type Variable struct {
+	Name string
+
+	// Type is the declared type constraint, cty.DynamicPseudoType if none.
+	Type cty.Type
+
+	// Required is true if the variable has no default value.
+	Required bool
+
+	// Sensitive is true if the variable is declared `sensitive = true`.
+	Sensitive bool
+
+	// Range is the position of the declaration.
+	Range hcl.Range
+}
+
+// moduleSchema and variableSchema are the subsets of the Terraform
+// configuration schema used for linting
+var (
+	moduleSchema = &hcl.BodySchema{
+		Blocks: []hcl.BlockHeaderSchema{
+			{Type: "variable", LabelNames: []string{"name"}},
+		},
+	}
+	variableSchema = &hcl.BodySchema{
+		Attributes: []hcl.AttributeSchema{
+			{Name: "type"},
+			{Name: "default"},
+			{Name: "sensitive"},
+		},
+	}
+)
+
+// parseVariables parses the variables declared by the configuration files
+// directly within a component directory, keyed by name.
+func parseVariables(fsys fs.FS, parser *hclparse.Parser, dir string) (map[string]Variable, hcl.Diagnostics) {
+	variables := map[string]Variable{}
+	var diags hcl.Diagnostics
+
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, hcl.Diagnostics{{
+			Severity: hcl.DiagError,
+			Summary:  "Failed to read component",
+			Detail:   err.Error(),
+			Subject:  &hcl.Range{Filename: dir},
+		}}
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !slices.ContainsFunc(discovery.TERRAFORM_EXTS, func(ext string) bool { return strings.HasSuffix(name, ext) }) {
+			continue
+		}
+
+		file, fileDiags := parseFile(fsys, parser, path.Join(dir, name))
+		diags = append(diags, fileDiags...)
+		if file == nil {
+			continue
+		}
+
+		content, _, contentDiags := file.Body.PartialContent(moduleSchema)
+		diags = append(diags, contentDiags...)
+
+		for _, block := range content.Blocks {
+			v, varDiags := decodeVariable(block)
+			diags = append(diags, varDiags...)
+			variables[v.Name] = v
+		}
+	}
+
+	return variables, diags
+}
+
+// decodeVariable decodes the attributes of a `variable` block needed to lint
+// values assigned to it.
+func decodeVariable(block *hcl.Block) (Variable, hcl.Diagnostics) {
+	v := Variable{
+		Name:     block.Labels[0],
+		Type:     cty.DynamicPseudoType,
+		Required: true,
+		Range:    block.DefRange,
+	}
+
+	attrs, _, diags := block.Body.PartialContent(variableSchema)
+
+	if attr, ok := attrs.Attributes["type"]; ok {
+		ty, tyDiags := typeexpr.TypeConstraint(attr.Expr)
+		diags = append(diags, tyDiags...)
+		if !tyDiags.HasErrors() {
+			v.Type = ty
+		}
+	}
+
+	if _, ok := attrs.Attributes["default"]; ok {
+		v.Required = false
+	}
+
+	if attr, ok := attrs.Attributes["sensitive"]; ok {
+		val, valDiags := attr.Expr.Value(nil)
+		diags = append(diags, valDiags...)
+		if !valDiags.HasErrors() && val.Type() == cty.Bool && val.IsKnown() && !val.IsNull() {
+			v.Sensitive = val.True()
+		}
+	}
+
+	return v, diags
+}
+
+// parseFile parses a native syntax or JSON HCL file, based on its extension.
+func parseFile(fsys fs.FS, parser *hclparse.Parser, name string) (*hcl.File, hcl.Diagnostics) {
+	src, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, hcl.Diagnostics{{
+			Severity: hcl.DiagError,
+			Summary:  "Failed to read file",
+			Detail:   err.Error(),
+			Subject:  &hcl.Range{Filename: name},
+		}}
+	}
+
+	if strings.HasSuffix(name, ".json") {
+		return parser.ParseJSON(src, name)
+	}
+	return parser.ParseHCL(src, name)
+}
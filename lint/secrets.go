@@ -0,0 +1,173 @@
+package lint
+
+import (
+	"fmt"
+	"io/fs"
+	"math"
+	"regexp"
+	"strings"
+
+	"github.com/3bbbeau/tfvars-atlantis-config/repocfg"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/zclconf/go-cty/cty"
+)
+
+const (
+	// SENSITIVE_RULE reports values for variables declared `sensitive = true`
+	SENSITIVE_RULE Rule = "sensitive"
+	// PATTERN_RULE reports values matching the format of a well-known secret
+	PATTERN_RULE Rule = "secret-pattern"
+	// ENTROPY_RULE reports random looking values, likely to be secrets
+	ENTROPY_RULE Rule = "high-entropy"
+)
+
+// secretPatterns are the formats of well-known credentials, by name
+var secretPatterns = []struct {
+	name string
+	re   *regexp.Regexp
+}{
+	{"AWS access key ID", regexp.MustCompile(`\b(AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"private key", regexp.MustCompile(`-----BEGIN ([A-Z]+ )*PRIVATE KEY-----`)},
+	{"GitHub token", regexp.MustCompile(`\b(gh[pousr]|github_pat)_[A-Za-z0-9_]{36,}\b`)},
+	{"Slack token", regexp.MustCompile(`\bxox[abeoprs]-[A-Za-z0-9-]{10,}`)},
+	{"Google API key", regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`)},
+	{"Stripe secret key", regexp.MustCompile(`\b[rs]k_live_[0-9A-Za-z]{24,}\b`)},
+}
+
+var hexString = regexp.MustCompile(`^[0-9a-fA-F]+$`)
+
+const (
+	// Strings shorter than these lengths are too short to be judged by
+	// their entropy.
+	MIN_ENTROPY_LENGTH     = 20
+	MIN_HEX_ENTROPY_LENGTH = 32
+
+	// Shannon entropy, in bits per character, above which a string is
+	// considered random. Hex strings have at most 4 bits per character.
+	ENTROPY_THRESHOLD     = 4.5
+	HEX_ENTROPY_THRESHOLD = 3.0
+)
+
+// ScanSecrets checks the values in the Terraform variable files of each
+// component for secrets, reporting:
+//
+//   - values for variables the component declares `sensitive = true`
+//   - strings matching the format of well-known credentials
+//   - random looking strings, by their Shannon entropy
+//
+// Secrets should be passed to Terraform via TF_VAR_ environment variables
+// or a secret store instead.
+func ScanSecrets(fsys fs.FS, components []repocfg.Component) ([]Finding, error) {
+	findings := []Finding{}
+	parser := hclparse.NewParser()
+
+	for _, c := range components {
+		variables, diags := parseVariables(fsys, parser, c.Path)
+		findings = append(findings, diagFindings(c, "", diags)...)
+
+		for _, v := range c.VarFiles {
+			attrs, diags := parseValues(fsys, parser, v.Path)
+			findings = append(findings, diagFindings(c, v.Environment, diags)...)
+			findings = append(findings, scanValues(c, v.Environment, variables, attrs)...)
+		}
+	}
+
+	return findings, nil
+}
+
+// scanValues checks each value assigned in a variable file for secrets,
+// reporting at most one finding per variable.
+func scanValues(c repocfg.Component, env string, variables map[string]Variable, attrs hcl.Attributes) []Finding {
+	findings := []Finding{}
+	base := Finding{Component: c.Path, Environment: env}
+
+	for _, attr := range sortedAttributes(attrs) {
+		val, diags := attr.Expr.Value(nil)
+		if diags.HasErrors() || val.IsNull() {
+			continue
+		}
+
+		f := base.at(attr.Expr.Range())
+		if v, ok := variables[attr.Name]; ok && v.Sensitive {
+			f.Rule = SENSITIVE_RULE
+			f.Message = fmt.Sprintf("variable %q is declared sensitive at %s:%d, its value must not be committed, set TF_VAR_%s instead", attr.Name, v.Range.Filename, v.Range.Start.Line, attr.Name)
+			findings = append(findings, f)
+			continue
+		}
+
+		if rule, reason := scanValue(val); rule != "" {
+			f.Rule = rule
+			f.Message = fmt.Sprintf("variable %q looks like it contains a secret (%s), set TF_VAR_%s instead", attr.Name, reason, attr.Name)
+			findings = append(findings, f)
+		}
+	}
+
+	return findings
+}
+
+// scanValue returns the rule and reason for the first string within the
+// value that looks like a secret, or an empty rule if none do.
+func scanValue(val cty.Value) (Rule, string) {
+	if !val.IsKnown() || val.IsNull() {
+		return "", ""
+	}
+
+	ty := val.Type()
+	switch {
+	case ty == cty.String:
+		return scanString(val.AsString())
+	case ty.IsListType(), ty.IsSetType(), ty.IsTupleType(), ty.IsMapType(), ty.IsObjectType():
+		for it := val.ElementIterator(); it.Next(); {
+			_, elem := it.Element()
+			if rule, reason := scanValue(elem); rule != "" {
+				return rule, reason
+			}
+		}
+	}
+	return "", ""
+}
+
+// scanString returns the rule and reason if the string looks like a secret
+func scanString(s string) (Rule, string) {
+	for _, p := range secretPatterns {
+		if p.re.MatchString(s) {
+			return PATTERN_RULE, p.name
+		}
+	}
+
+	// Sentences, URLs and ARNs have a high entropy without being random.
+	if strings.ContainsAny(s, " \t\n") || strings.Contains(s, "://") || strings.HasPrefix(s, "arn:") {
+		return "", ""
+	}
+
+	minLength, threshold := MIN_ENTROPY_LENGTH, ENTROPY_THRESHOLD
+	if hexString.MatchString(s) {
+		minLength, threshold = MIN_HEX_ENTROPY_LENGTH, HEX_ENTROPY_THRESHOLD
+	}
+	if len(s) < minLength {
+		return "", ""
+	}
+
+	if e := entropy(s); e > threshold {
+		return ENTROPY_RULE, fmt.Sprintf("entropy of %.1f bits per character", e)
+	}
+	return "", ""
+}
+
+// entropy returns the Shannon entropy of the string in bits per character
+func entropy(s string) float64 {
+	counts := map[rune]int{}
+	n := 0
+	for _, r := range s {
+		counts[r]++
+		n++
+	}
+
+	e := 0.0
+	for _, count := range counts {
+		p := float64(count) / float64(n)
+		e -= p * math.Log2(p)
+	}
+	return e
+}
@@ -2,6 +2,7 @@ package logger
 
 import (
 	"context"
+	"fmt"
 
 	"go.uber.org/zap"
 )
@@ -27,3 +28,63 @@ func FromContext(ctx context.Context) *zap.Logger {
 
 	return v.(*zap.Logger)
 }
+
+const (
+	JSON_FORMAT    = "json"
+	CONSOLE_FORMAT = "console"
+)
+
+// Options represents the configuration of the logger.
+type Options struct {
+	// Development uses zap's development config, logging at debug level to
+	// the console with stack traces for warnings, rather than its production
+	// config.
+	Development bool
+
+	// Format is the encoding of the logs, JSON_FORMAT or CONSOLE_FORMAT.
+	// Defaults to that of the config.
+	Format string
+
+	// Level is the minimum level logged, e.g. "debug" or "warn". Defaults to
+	// that of the config.
+	Level string
+
+	// File is the path of the file the logs are appended to. Defaults to
+	// stderr, so that logs never mix with output written to stdout.
+	File string
+}
+
+// New returns a logger configured by the options.
+func New(opts Options) (*zap.Logger, error) {
+	cfg := zap.NewProductionConfig()
+	if opts.Development {
+		cfg = zap.NewDevelopmentConfig()
+	}
+
+	switch opts.Format {
+	case "":
+	case JSON_FORMAT:
+		cfg.Encoding = JSON_FORMAT
+		cfg.EncoderConfig = zap.NewProductionEncoderConfig()
+	case CONSOLE_FORMAT:
+		cfg.Encoding = CONSOLE_FORMAT
+		cfg.EncoderConfig = zap.NewDevelopmentEncoderConfig()
+	default:
+		return nil, fmt.Errorf("unknown log format %q, must be one of: %s, %s", opts.Format, JSON_FORMAT, CONSOLE_FORMAT)
+	}
+
+	if opts.Level != "" {
+		level, err := zap.ParseAtomicLevel(opts.Level)
+		if err != nil {
+			return nil, fmt.Errorf("log level: %w", err)
+		}
+		cfg.Level = level
+	}
+
+	if opts.File != "" {
+		cfg.OutputPaths = []string{opts.File}
+		cfg.ErrorOutputPaths = []string{opts.File}
+	}
+
+	return cfg.Build()
+}
@@ -0,0 +1,36 @@
+package logger
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Tests the New function writes logs of the configured format and level to
+// the log file, and rejects unknown formats and levels.
+func Test_New(t *testing.T) {
+	t.Parallel()
+
+	file := filepath.Join(t.TempDir(), "log.json")
+	z, err := New(Options{Format: JSON_FORMAT, Level: "warn", File: file})
+	if err != nil {
+		t.Fatalf("New() error: %s", err)
+	}
+	z.Info("ignored")
+	z.Warn("claimed var file")
+
+	b, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(b); strings.Contains(got, "ignored") || !strings.HasPrefix(got, `{"level":"warn"`) {
+		t.Errorf("New() wrote %q", got)
+	}
+
+	for _, opts := range []Options{{Format: "xml"}, {Level: "loud"}} {
+		if _, err := New(opts); err == nil {
+			t.Errorf("New() expected error for %+v", opts)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package repocfg
+
+// Annotations represent the directives in a Terraform variable file
+// controlling the project generated from it, overriding the options and
+// policies for its environment, e.g. `# atlantis:autoplan=false`.
+type Annotations struct {
+	// Skip leaves the variable file out, generating no project from it.
+	Skip bool
+
+	// Autoplan enables or disables autoplan for the project, if set.
+	Autoplan *bool
+
+	// Workspace is the Terraform workspace of the project, if set, whether
+	// or not Options.UseWorkspaces is.
+	Workspace string
+
+	// ApplyRequirements, PlanRequirements and ImportRequirements override
+	// those of the environment's policy, if set.
+	ApplyRequirements  []string
+	PlanRequirements   []string
+	ImportRequirements []string
+}
+
+// annotate returns the policy with the settings of the annotations
+// overriding its own.
+func (policy EnvironmentPolicy) annotate(a Annotations) EnvironmentPolicy {
+	if a.Autoplan != nil {
+		policy.Autoplan = a.Autoplan
+	}
+	if a.ApplyRequirements != nil {
+		policy.ApplyRequirements = a.ApplyRequirements
+	}
+	if a.PlanRequirements != nil {
+		policy.PlanRequirements = a.PlanRequirements
+	}
+	if a.ImportRequirements != nil {
+		policy.ImportRequirements = a.ImportRequirements
+	}
+	return policy
+}
+
+// ProjectVarFiles returns the variable files of the component that projects
+// are generated from, in order, leaving out those annotated to be skipped.
+func (c Component) ProjectVarFiles() []VarFile {
+	var varFiles []VarFile
+	for _, v := range c.VarFiles {
+		if !v.Annotations.Skip {
+			varFiles = append(varFiles, v)
+		}
+	}
+	return varFiles
+}
+
+// ProjectEnvironment is an environment of a component and the variable files
+// its project is planned with, e.g. all of those in `envs/dev/` with the
+// directory strategy.
+type ProjectEnvironment struct {
+	Name     string
+	VarFiles []VarFile
+}
+
+// ProjectEnvironments groups the variable files of the component that
+// projects are generated from by their environment, in the order of the
+// first variable file of each environment.
+func (c Component) ProjectEnvironments() []ProjectEnvironment {
+	var envs []ProjectEnvironment
+	index := map[string]int{}
+	for _, v := range c.ProjectVarFiles() {
+		env := v.environment()
+		i, ok := index[env]
+		if !ok {
+			i = len(envs)
+			index[env] = i
+			envs = append(envs, ProjectEnvironment{Name: env})
+		}
+		envs[i].VarFiles = append(envs[i].VarFiles, v)
+	}
+	return envs
+}
+
+// annotations returns the annotations of the environment's variable files,
+// those of later files overriding earlier ones.
+func (e ProjectEnvironment) annotations() Annotations {
+	var a Annotations
+	for _, v := range e.VarFiles {
+		if v.Annotations.Autoplan != nil {
+			a.Autoplan = v.Annotations.Autoplan
+		}
+		if v.Annotations.Workspace != "" {
+			a.Workspace = v.Annotations.Workspace
+		}
+		if v.Annotations.ApplyRequirements != nil {
+			a.ApplyRequirements = v.Annotations.ApplyRequirements
+		}
+		if v.Annotations.PlanRequirements != nil {
+			a.PlanRequirements = v.Annotations.PlanRequirements
+		}
+		if v.Annotations.ImportRequirements != nil {
+			a.ImportRequirements = v.Annotations.ImportRequirements
+		}
+	}
+	return a
+}
+
+// paths returns the paths of the environment's variable files relative to
+// the component directory.
+func (e ProjectEnvironment) paths(dir string) []string {
+	paths := make([]string, 0, len(e.VarFiles))
+	for _, v := range e.VarFiles {
+		paths = append(paths, relativeTo(dir, v.Path))
+	}
+	return paths
+}
@@ -0,0 +1,92 @@
+package repocfg
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/runatlantis/atlantis/server/core/config/raw"
+)
+
+// Tests the annotations of a var file override the options and policies of
+// its project, and skipped var files have no project or workflow.
+func Test_ProjectsFromAnnotations(t *testing.T) {
+	t.Parallel()
+
+	component := Component{
+		Path: "network",
+		VarFiles: []VarFile{
+			{Path: "network/dev.tfvars", Environment: "dev", Annotations: Annotations{Skip: true}},
+			{
+				Path:        "network/prd.tfvars",
+				Environment: "prd",
+				Annotations: Annotations{
+					Autoplan:          ptr(false),
+					Workspace:         "prod-eu",
+					ApplyRequirements: []string{"approved"},
+				},
+			},
+			{Path: "network/stg.tfvars", Environment: "stg"},
+		},
+		BackendConfigs: map[string]string{
+			"dev": "network/backend/dev.hcl",
+		},
+	}
+
+	options := Options{
+		Autoplan: true,
+		Policies: []EnvironmentPolicy{
+			{Environment: regexp.MustCompile("."), ApplyRequirements: []string{"mergeable"}},
+		},
+	}
+
+	want := []ExtRawProject{
+		{
+			Project: raw.Project{
+				Name:              ptr("network-prd"),
+				Dir:               ptr("network"),
+				Workspace:         ptr("prod-eu"),
+				ApplyRequirements: []string{"approved"},
+				Autoplan:          &raw.Autoplan{Enabled: ptr(false)},
+			},
+			Component:   "network",
+			Environment: "prd",
+			VarFiles:    []string{"network/prd.tfvars"},
+		},
+		{
+			Project: raw.Project{
+				Name:              ptr("network-stg"),
+				Dir:               ptr("network"),
+				ApplyRequirements: []string{"mergeable"},
+				Autoplan: &raw.Autoplan{
+					Enabled:      ptr(true),
+					WhenModified: []string{"*.tf", "stg.tfvars"},
+				},
+			},
+			Component:   "network",
+			Environment: "stg",
+			VarFiles:    []string{"network/stg.tfvars"},
+		},
+	}
+
+	got, err := ProjectsFrom(component, options)
+	if err != nil {
+		t.Fatalf("ProjectsFrom() error: %s", err)
+	}
+
+	if !cmp.Equal(got, want) {
+		t.Errorf(`ProjectsFrom()
+		diff %s`, cmp.Diff(got, want))
+	}
+
+	if workflows := WorkflowsFrom(component); len(workflows) != 0 {
+		t.Errorf("WorkflowsFrom() got workflows %v for skipped var files", workflows)
+	}
+
+	// A component with only skipped var files has no default project
+	component.VarFiles = component.VarFiles[:1]
+	got, err = ProjectsFrom(component, Options{IncludeNoVarFiles: true})
+	if err != nil || len(got) != 0 {
+		t.Errorf("ProjectsFrom() = %v, %v, want no projects", got, err)
+	}
+}
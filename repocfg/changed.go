@@ -0,0 +1,114 @@
+package repocfg
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/moby/patternmatcher"
+	"github.com/runatlantis/atlantis/server/core/config/raw"
+)
+
+// filterChanged removes the projects not affected by any of the changed
+// files from the repo config, along with the workflows only they used.
+//
+// A project is affected by the files matching its `when_modified` patterns,
+// or Atlantis' default patterns if it has none, matched the way Atlantis
+// matches them against the files modified by a pull request. It is also
+// affected by the files directly within the directories of the local modules
+// its component calls, directly or through other local modules.
+func (rc *ExtRawRepoCfg) filterChanged(components []Component, changed []string) error {
+	byPath := map[string]Component{}
+	for _, c := range components {
+		byPath[c.Path] = c
+	}
+
+	var projects []ExtRawProject
+	workflows := map[string]raw.Workflow{}
+	for _, p := range rc.Projects {
+		ok, err := p.affected(moduleClosure(byPath, *p.Dir), changed)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+
+		projects = append(projects, p)
+		if p.Workflow != nil {
+			if w, ok := rc.Workflows[*p.Workflow]; ok {
+				workflows[*p.Workflow] = w
+			}
+		}
+	}
+
+	rc.Projects = projects
+	if len(rc.Workflows) > 0 {
+		rc.Workflows = workflows
+	}
+	return nil
+}
+
+// affected returns true if any of the changed files, relative to the repo
+// root, match the project's `when_modified` patterns or are in one of the
+// module directories.
+func (p ExtRawProject) affected(modules []string, changed []string) (bool, error) {
+	whenModified := raw.DefaultAutoPlanWhenModified
+	if p.Autoplan != nil && len(p.Autoplan.WhenModified) > 0 {
+		whenModified = p.Autoplan.WhenModified
+	}
+
+	// The patterns are relative to the project directory, while the changed
+	// files are relative to the repo root. Exclusions keep their leading "!".
+	patterns := make([]string, 0, len(whenModified))
+	for _, wm := range whenModified {
+		wm = strings.TrimSpace(wm)
+		exclusion := strings.HasPrefix(wm, "!")
+		wm = path.Join(*p.Dir, strings.TrimPrefix(wm, "!"))
+		if exclusion {
+			wm = "!" + wm
+		}
+		patterns = append(patterns, wm)
+	}
+
+	pm, err := patternmatcher.New(patterns)
+	if err != nil {
+		return false, fmt.Errorf("project %s: matching changed files with patterns %v: %w", *p.Name, whenModified, err)
+	}
+
+	for _, file := range changed {
+		if slices.Contains(modules, path.Dir(file)) {
+			return true, nil
+		}
+
+		match, err := pm.MatchesOrParentMatches(file)
+		if err != nil {
+			return false, fmt.Errorf("project %s: matching changed file %s: %w", *p.Name, file, err)
+		}
+		if match {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// moduleClosure returns the directories of the local modules called by the
+// component at dir, directly or through other local modules.
+func moduleClosure(components map[string]Component, dir string) []string {
+	var modules []string
+	queue := slices.Clone(components[dir].Modules)
+
+	for len(queue) > 0 {
+		m := queue[0]
+		queue = queue[1:]
+		if slices.Contains(modules, m) {
+			continue
+		}
+		modules = append(modules, m)
+		queue = append(queue, components[m].Modules...)
+	}
+
+	return modules
+}
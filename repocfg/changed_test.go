@@ -0,0 +1,119 @@
+package repocfg
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// Tests NewRepoCfg only generates the projects affected by the changed files
+// when they are given.
+func Test_NewRepoCfgChangedFiles(t *testing.T) {
+	t.Parallel()
+
+	components := []Component{
+		{
+			Path:           "db",
+			VarFiles:       []VarFile{{Path: "db/dev.tfvars", Environment: "dev"}, {Path: "db/prd.tfvars", Environment: "prd"}},
+			BackendConfigs: map[string]string{"prd": "db/backend/prd.hcl"},
+		},
+		{
+			Path:    "modules/vpc",
+			Modules: []string{"modules/subnets"},
+		},
+		{
+			Path: "modules/subnets",
+		},
+		{
+			Path:     "network",
+			VarFiles: []VarFile{{Path: "network/dev.tfvars", Environment: "dev"}},
+			Modules:  []string{"modules/vpc"},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		autoplan bool
+		changed  []string
+		want     []string
+	}{
+		{
+			name:     "All",
+			autoplan: true,
+		},
+		{
+			name:     "None",
+			autoplan: true,
+			changed:  []string{},
+			want:     []string{},
+		},
+		{
+			name:     "VarFile",
+			autoplan: true,
+			changed:  []string{"db/prd.tfvars"},
+			want:     []string{"db-prd"},
+		},
+		{
+			name:     "BackendConfig",
+			autoplan: true,
+			changed:  []string{"db/backend/prd.hcl"},
+			want:     []string{"db-prd"},
+		},
+		{
+			name:     "SharedFile",
+			autoplan: true,
+			changed:  []string{"README.md", "db/main.tf"},
+			want:     []string{"db-dev", "db-prd"},
+		},
+		{
+			name:     "DefaultPatterns",
+			autoplan: false,
+			changed:  []string{"db/prd.tfvars", "network/.terraform.lock.hcl"},
+			want:     []string{"db-dev", "db-prd", "network-dev"},
+		},
+		{
+			name:     "TransitiveModule",
+			autoplan: true,
+			changed:  []string{"modules/subnets/main.tf"},
+			want:     []string{"network-dev"},
+		},
+		{
+			name:     "NestedInModule",
+			autoplan: true,
+			changed:  []string{"modules/vpc/files/policy.json"},
+			want:     []string{},
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			opts := Options{Autoplan: tc.autoplan}
+			if tc.changed != nil {
+				opts.ChangedFiles = tc.changed
+			}
+
+			cfg, err := NewRepoCfg(components, opts)
+			if err != nil {
+				t.Fatalf("NewRepoCfg() error: %s", err)
+			}
+
+			got := []string{}
+			for _, p := range cfg.Projects {
+				got = append(got, *p.Name)
+			}
+
+			want := tc.want
+			if want == nil {
+				want = []string{"db-dev", "db-prd", "network-dev"}
+			}
+
+			if !cmp.Equal(got, want) {
+				t.Errorf(`NewRepoCfg()
+				diff %s`, cmp.Diff(got, want))
+			}
+		})
+	}
+}
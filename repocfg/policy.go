@@ -0,0 +1,98 @@
+package repocfg
+
+import (
+	"regexp"
+
+	"github.com/runatlantis/atlantis/server/core/config/raw"
+)
+
+// EnvironmentPolicy represents the settings of the projects for the
+// environments matching a regular expression, e.g. requiring approval to
+// apply to `prod`.
+type EnvironmentPolicy struct {
+	// Environment is matched against the environment name of each project.
+	// It is not anchored, use "^prod$" to only match `prod`.
+	Environment *regexp.Regexp
+
+	// ApplyRequirements, PlanRequirements and ImportRequirements are the
+	// requirements Atlantis checks before running the command, e.g.
+	// "approved", "mergeable" or "undiverged".
+	ApplyRequirements  []string
+	PlanRequirements   []string
+	ImportRequirements []string
+
+	// Autoplan enables or disables autoplan for the projects, overriding
+	// Options.Autoplan, if set.
+	Autoplan *bool
+
+	// Branch is a regular expression matching the base branches of the pull
+	// requests the projects are planned for, e.g. "^main$".
+	Branch string
+}
+
+// policy returns the policy for an environment, merged from the policies
+// matching it in order, so that the settings of later policies override
+// those of earlier ones.
+func (opts Options) policy(env string) EnvironmentPolicy {
+	var merged EnvironmentPolicy
+
+	for _, p := range opts.Policies {
+		if p.Environment == nil || !p.Environment.MatchString(env) {
+			continue
+		}
+
+		if p.ApplyRequirements != nil {
+			merged.ApplyRequirements = p.ApplyRequirements
+		}
+		if p.PlanRequirements != nil {
+			merged.PlanRequirements = p.PlanRequirements
+		}
+		if p.ImportRequirements != nil {
+			merged.ImportRequirements = p.ImportRequirements
+		}
+		if p.Autoplan != nil {
+			merged.Autoplan = p.Autoplan
+		}
+		if p.Branch != "" {
+			merged.Branch = p.Branch
+		}
+	}
+
+	return merged
+}
+
+// autoplan returns whether autoplan is enabled for the projects of a policy
+func (opts Options) autoplan(policy EnvironmentPolicy) bool {
+	if policy.Autoplan != nil {
+		return *policy.Autoplan
+	}
+	return opts.Autoplan
+}
+
+// Policy sets the requirements and branch of the project from the policy
+// for its environment. Autoplan is set by AutoPlan, or disabled by
+// DisableAutoPlan.
+func (p *ExtRawProject) Policy(policy EnvironmentPolicy) {
+	if policy.ApplyRequirements != nil {
+		p.ApplyRequirements = policy.ApplyRequirements
+	}
+	if policy.PlanRequirements != nil {
+		p.PlanRequirements = policy.PlanRequirements
+	}
+	if policy.ImportRequirements != nil {
+		p.ImportRequirements = policy.ImportRequirements
+	}
+
+	// Atlantis expects the branch regex between slashes
+	if policy.Branch != "" {
+		p.Branch = ptr("/" + policy.Branch + "/")
+	}
+}
+
+// DisableAutoPlan disables autoplan for the project, which Atlantis
+// otherwise enables by default.
+func (p *ExtRawProject) DisableAutoPlan() {
+	p.Autoplan = &raw.Autoplan{
+		Enabled: ptr(false),
+	}
+}
@@ -0,0 +1,142 @@
+package repocfg
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/runatlantis/atlantis/server/core/config/raw"
+)
+
+// Tests the projects of each environment get the settings of the policies
+// matching it, with later policies overriding earlier ones.
+func Test_ProjectsFromPolicies(t *testing.T) {
+	t.Parallel()
+
+	component := Component{
+		Path: "network",
+		VarFiles: []VarFile{
+			{Path: "network/dev.tfvars", Environment: "dev"},
+			{Path: "network/prod.tfvars", Environment: "prod"},
+			{Path: "network/prod-eu.tfvars", Environment: "prod-eu"},
+		},
+	}
+
+	options := Options{
+		Autoplan: true,
+		Policies: []EnvironmentPolicy{
+			{
+				Environment:       regexp.MustCompile("^prod"),
+				ApplyRequirements: []string{"approved", "mergeable", "undiverged"},
+				Autoplan:          ptr(false),
+			},
+			{
+				Environment:      regexp.MustCompile("^prod-eu$"),
+				PlanRequirements: []string{"undiverged"},
+				Branch:           "^main$",
+			},
+		},
+	}
+
+	want := []ExtRawProject{
+		{
+			Project: raw.Project{
+				Name: ptr("network-dev"),
+				Dir:  ptr("network"),
+				Autoplan: &raw.Autoplan{
+					Enabled:      ptr(true),
+					WhenModified: []string{"*.tf", "dev.tfvars"},
+				},
+			},
+			Component:   "network",
+			Environment: "dev",
+			VarFiles:    []string{"network/dev.tfvars"},
+		},
+		{
+			Project: raw.Project{
+				Name:              ptr("network-prod"),
+				Dir:               ptr("network"),
+				ApplyRequirements: []string{"approved", "mergeable", "undiverged"},
+				Autoplan:          &raw.Autoplan{Enabled: ptr(false)},
+			},
+			Component:   "network",
+			Environment: "prod",
+			VarFiles:    []string{"network/prod.tfvars"},
+		},
+		{
+			Project: raw.Project{
+				Name:              ptr("network-prod-eu"),
+				Dir:               ptr("network"),
+				Branch:            ptr("/^main$/"),
+				ApplyRequirements: []string{"approved", "mergeable", "undiverged"},
+				PlanRequirements:  []string{"undiverged"},
+				Autoplan:          &raw.Autoplan{Enabled: ptr(false)},
+			},
+			Component:   "network",
+			Environment: "prod-eu",
+			VarFiles:    []string{"network/prod-eu.tfvars"},
+		},
+	}
+
+	got, err := ProjectsFrom(component, options)
+	if err != nil {
+		t.Fatalf("ProjectsFrom() error: %s", err)
+	}
+
+	if !cmp.Equal(got, want) {
+		t.Errorf(`ProjectsFrom()
+		diff %s`, cmp.Diff(got, want))
+	}
+}
+
+// Tests a policy enables autoplan for an environment when it is otherwise
+// disabled.
+func Test_ProjectsFromPolicyAutoplan(t *testing.T) {
+	t.Parallel()
+
+	component := Component{
+		Path:     "network",
+		VarFiles: []VarFile{{Path: "network/dev.tfvars", Environment: "dev"}},
+	}
+
+	options := Options{
+		Policies: []EnvironmentPolicy{
+			{Environment: regexp.MustCompile("dev"), Autoplan: ptr(true)},
+		},
+	}
+
+	want := &raw.Autoplan{
+		Enabled:      ptr(true),
+		WhenModified: []string{"*.tf", "dev.tfvars"},
+	}
+
+	got, err := ProjectsFrom(component, options)
+	if err != nil {
+		t.Fatalf("ProjectsFrom() error: %s", err)
+	}
+
+	if !cmp.Equal(got[0].Autoplan, want) {
+		t.Errorf(`ProjectsFrom()
+		diff %s`, cmp.Diff(got[0].Autoplan, want))
+	}
+}
+
+// Tests an invalid requirement in a policy fails validation of the project
+func Test_ProjectsFromPolicyInvalid(t *testing.T) {
+	t.Parallel()
+
+	component := Component{
+		Path:     "network",
+		VarFiles: []VarFile{{Path: "network/dev.tfvars", Environment: "dev"}},
+	}
+
+	options := Options{
+		Policies: []EnvironmentPolicy{
+			{Environment: regexp.MustCompile("dev"), ApplyRequirements: []string{"aproved"}},
+		},
+	}
+
+	if _, err := ProjectsFrom(component, options); err == nil {
+		t.Errorf("ProjectsFrom() expected error for invalid apply requirement")
+	}
+}
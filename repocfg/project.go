@@ -2,15 +2,32 @@ package repocfg
 
 import (
 	"fmt"
-	"path/filepath"
+	"strings"
 
 	"github.com/hashicorp/go-version"
 	"github.com/runatlantis/atlantis/server/core/config/raw"
 )
 
-// ExtRawProject extends the raw.Project type to add additional methods
+// ExtRawProject extends the raw.Project type to add additional methods, and
+// fields not yet supported by raw.Project
 type ExtRawProject struct {
-	raw.Project
+	raw.Project `yaml:",inline"`
+
+	TerraformDistribution *string `yaml:"terraform_distribution,omitempty"`
+
+	// Comment is written as a comment above the project when marshalled.
+	Comment string `yaml:"-"`
+
+	// Component, Environment and VarFiles are the component, environment and
+	// variable files the project was generated from, which are not
+	// marshalled. A default project has no environment or variable files.
+	Component   string   `yaml:"-"`
+	Environment string   `yaml:"-"`
+	VarFiles    []string `yaml:"-"`
+
+	// TerraformVersionSource is the version file or option the Terraform
+	// version of the project was taken from, empty if it has none.
+	TerraformVersionSource string `yaml:"-"`
 }
 
 // ErrProjectFrom represents an error when creating an Atlantis project from a
@@ -31,38 +48,99 @@ func (e ErrNewProject) Error() string {
 
 // ProjectsFrom creates an Atlantis project from a Terraform component and
 // its associated Terraform variable files.
+//
+// A component without any Terraform variable files has no projects, unless
+// opts.IncludeNoVarFiles is set and it is not called as a local module by
+// another, in which case it has a single project in the default workspace,
+// see defaultProjectFrom. The variable files of the same environment, e.g.
+// `envs/dev/terraform.tfvars` and `envs/dev/extra.tfvars`, make a single
+// project planned with all of them. Variable files annotated to be skipped
+// have no project, and a component whose variable files are all skipped has
+// none at all.
 func ProjectsFrom(c Component, opts Options) ([]ExtRawProject, error) {
 	var projects []ExtRawProject
 
-	for _, v := range c.VarFiles {
+	// A local module without variable files is planned through the
+	// components calling it.
+	if len(c.VarFiles) == 0 && opts.IncludeNoVarFiles && !c.IsModule {
+		p, err := defaultProjectFrom(c, opts)
+		if err != nil {
+			return nil, err
+		}
+		return append(projects, p), nil
+	}
+
+	for _, e := range c.ProjectEnvironments() {
+		env := e.Name
+		annotations := e.annotations()
 		p := ExtRawProject{
 			Project: raw.Project{
-				Name: ptr(friendlyName(c.Path, v)),
+				Name: ptr(friendlyName(c.Path, env)),
 
 				// The directory of this project relative to the repo root.
 				Dir: ptr(c.Path),
 			},
+			Component:   c.Path,
+			Environment: env,
+		}
+		for _, v := range e.VarFiles {
+			p.VarFiles = append(p.VarFiles, v.Path)
+		}
+
+		if opts.ProvenanceComments {
+			p.Comment = provenance(c, e)
 		}
 
 		if opts.UseWorkspaces {
-			// Terraform workspaces are represented by the Terraform variable file
-			// names.
+			// Terraform workspaces are represented by the environment of the
+			// Terraform variable files.
 			//
 			// Example:
 			//
 			// dev.tfvars -> dev
-			// stg.tfvars.json -> stg
-			p.Workspace = ptr(pathWithoutExtension(v))
+			// envs/stg/terraform.tfvars -> stg
+			p.Workspace = ptr(env)
+		}
+		if annotations.Workspace != "" {
+			p.Workspace = ptr(annotations.Workspace)
 		}
 
-		// Generate a default Terraform version for the project if enabled
-		if opts.DefaultTerraformVersion != "" {
-			p.DefaultTerraformVersion(opts.DefaultTerraformVersion)
+		// Projects with a backend config for their environment use a
+		// workflow of the same name, see WorkflowsFrom.
+		backendConfig, hasBackendConfig := c.BackendConfigs[env]
+		if hasBackendConfig {
+			p.Workflow = p.Name
 		}
 
-		// Generate autoplan configuration for the project if enabled
-		if opts.Autoplan {
-			p.AutoPlan(v)
+		// Generate a Terraform version for the project if pinned by the
+		// component or enabled
+		p.terraformVersion(c, opts)
+
+		if d := opts.distribution(c); d != "" {
+			err := p.Distribution(d)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		policy := opts.policy(env).annotate(annotations)
+		p.Policy(policy)
+
+		// Generate autoplan configuration for the project if enabled, or
+		// disable it if a policy or annotation does, as Atlantis enables it
+		// by default.
+		switch {
+		case opts.autoplan(policy):
+			varFiles := e.paths(c.Path)
+			p.AutoPlan(varFiles[0], c.Extensions...)
+			p.Autoplan.WhenModified = append(p.Autoplan.WhenModified, varFiles[1:]...)
+			// The backend config is read by the project's workflow, so
+			// changing it triggers a plan too.
+			if hasBackendConfig {
+				p.Autoplan.WhenModified = append(p.Autoplan.WhenModified, relativeTo(c.Path, backendConfig))
+			}
+		case policy.Autoplan != nil:
+			p.DisableAutoPlan()
 		}
 
 		// We can validate the project using the Atlantis validate method
@@ -75,23 +153,169 @@ func ProjectsFrom(c Component, opts Options) ([]ExtRawProject, error) {
 	return projects, nil
 }
 
-// AutoPlan sets the autoplan configuration for the project
+// provenance returns the comment of a project naming the component and the
+// variable files of the environment it was generated from.
+func provenance(c Component, e ProjectEnvironment) string {
+	paths := make([]string, 0, len(e.VarFiles))
+	for _, v := range e.VarFiles {
+		paths = append(paths, v.Path)
+	}
+	if len(paths) == 1 {
+		return fmt.Sprintf("generated from component %s and var file %s", c.Path, paths[0])
+	}
+	return fmt.Sprintf("generated from component %s and var files %s", c.Path, strings.Join(paths, ", "))
+}
+
+// defaultProjectFrom creates the Atlantis project for a Terraform component
+// without any Terraform variable files, named after its directory and planned
+// in the default workspace. Its autoplan, if enabled, is only triggered by the
+// Terraform files.
+func defaultProjectFrom(c Component, opts Options) (ExtRawProject, error) {
+	p := ExtRawProject{
+		Project: raw.Project{
+			Name: ptr(friendlyName(c.Path, "")),
+			Dir:  ptr(c.Path),
+		},
+		Component: c.Path,
+	}
+
+	// The root component has no directory name to use
+	if c.Path == "." {
+		p.Name = ptr(raw.DefaultWorkspace)
+	}
+
+	if opts.ProvenanceComments {
+		p.Comment = fmt.Sprintf("generated from component %s without var files", c.Path)
+	}
+
+	p.terraformVersion(c, opts)
+
+	if d := opts.distribution(c); d != "" {
+		err := p.Distribution(d)
+		if err != nil {
+			return p, err
+		}
+	}
+
+	if opts.Autoplan {
+		p.AutoPlan("", c.Extensions...)
+	}
+
+	err := p.Validate()
+	if err != nil {
+		return p, fmt.Errorf("failed to validate project: %w", err)
+	}
+	return p, nil
+}
+
+// WorkflowsFrom creates the Atlantis workflows for the environments of a
+// Terraform component that have a backend config, keyed by the name of the
+// project using them.
+//
+// Each workflow initialises Terraform with the environment's backend config
+// and plans with each of the environment's Terraform variable files:
+//
+//	init -backend-config=backend/dev.hcl
+//	plan -var-file=envs/dev/terraform.tfvars -var-file=envs/dev/extra.tfvars
+//	apply
+func WorkflowsFrom(c Component) map[string]raw.Workflow {
+	workflows := map[string]raw.Workflow{}
+
+	for _, e := range c.ProjectEnvironments() {
+		env := e.Name
+		backendConfig, ok := c.BackendConfigs[env]
+		if !ok {
+			continue
+		}
+
+		varFileArgs := []string{}
+		for _, varFile := range e.paths(c.Path) {
+			varFileArgs = append(varFileArgs, "-var-file="+varFile)
+		}
+
+		workflows[friendlyName(c.Path, env)] = raw.Workflow{
+			Plan: &raw.Stage{
+				Steps: []raw.Step{
+					extraArgsStep("init", "-backend-config="+relativeTo(c.Path, backendConfig)),
+					extraArgsStep("plan", varFileArgs...),
+				},
+			},
+			Apply: &raw.Stage{
+				Steps: []raw.Step{
+					{Key: ptr("apply")},
+				},
+			},
+		}
+	}
+
+	return workflows
+}
+
+// extraArgsStep returns a workflow step for a built-in command with extra
+// arguments, e.g.:
+//
+//   - init:
+//     extra_args: ["-backend-config=backend/dev.hcl"]
+func extraArgsStep(name string, args ...string) raw.Step {
+	return raw.Step{
+		Map: map[string]map[string][]string{
+			name: {"extra_args": args},
+		},
+	}
+}
+
+// AutoPlan sets the autoplan configuration for the project, given the path
+// of the variable file relative to the project directory and the extensions
+// of the component's configuration files, ".tf" if none are given.
 //
 // This will trigger a plan when the Terraform files or the variable files
-// are modified.
-func (p *ExtRawProject) AutoPlan(v string) {
+// are modified. If the variable file is empty, only the Terraform files
+// trigger a plan.
+func (p *ExtRawProject) AutoPlan(v string, exts ...string) {
+	if len(exts) == 0 {
+		exts = []string{".tf"}
+	}
+
 	autoplan := &raw.Autoplan{
 		Enabled: ptr(true),
+	}
 
-		// Paths are relative to the project directory
-		WhenModified: []string{
-			"*.tf",
-			filepath.Base(v),
-		},
+	// Paths are relative to the project directory
+	for _, ext := range exts {
+		autoplan.WhenModified = append(autoplan.WhenModified, "*"+ext)
+	}
+	if v != "" {
+		autoplan.WhenModified = append(autoplan.WhenModified, v)
 	}
 	p.Autoplan = autoplan
 }
 
+// Distribution sets the Terraform distribution Atlantis runs for the project
+// if it is either "terraform" or "opentofu".
+func (p *ExtRawProject) Distribution(d string) error {
+	switch d {
+	case TERRAFORM_DISTRIBUTION, OPENTOFU_DISTRIBUTION:
+		p.TerraformDistribution = ptr(d)
+		return nil
+	default:
+		return fmt.Errorf("unknown terraform distribution %q, must be one of: %s, %s", d, TERRAFORM_DISTRIBUTION, OPENTOFU_DISTRIBUTION)
+	}
+}
+
+// terraformVersion sets the Terraform version pinned by the component, or the
+// default version of the options, and the source it was taken from, if any.
+// An invalid version is left empty without a source.
+func (p *ExtRawProject) terraformVersion(c Component, opts Options) {
+	v := opts.terraformVersion(c)
+	if v == "" {
+		return
+	}
+	p.DefaultTerraformVersion(v)
+	if *p.TerraformVersion != "" {
+		p.TerraformVersionSource = opts.terraformVersionSource(c)
+	}
+}
+
 // DefaultTerraformVersion sets the default Terraform version for the project
 // if the version is valid.
 func (p *ExtRawProject) DefaultTerraformVersion(v string) {
@@ -19,7 +19,7 @@ func Test_ProjectsFrom(t *testing.T) {
 		{
 			component: Component{
 				Path:     "test",
-				VarFiles: []string{"env.tfvars"},
+				VarFiles: []VarFile{{Path: "test/env.tfvars", Environment: "env"}},
 			},
 			options: Options{
 				Autoplan:                true,
@@ -42,6 +42,125 @@ func Test_ProjectsFrom(t *testing.T) {
 							},
 						},
 					},
+					Component:   "test",
+					Environment: "env",
+					VarFiles:    []string{"test/env.tfvars"},
+				},
+			},
+		},
+		{
+			component: Component{
+				Path: "test/novars",
+			},
+			options: Options{
+				Autoplan:          true,
+				IncludeNoVarFiles: true,
+				UseWorkspaces:     true,
+			},
+			want: []ExtRawProject{
+				{
+					Project: raw.Project{
+						Name: ptr("test-novars"),
+						Dir:  ptr("test/novars"),
+						Autoplan: &raw.Autoplan{
+							Enabled: ptr(true),
+							WhenModified: []string{
+								"*.tf",
+							},
+						},
+					},
+					Component: "test/novars",
+				},
+			},
+		},
+		{
+			component: Component{
+				Path: ".",
+			},
+			options: Options{
+				IncludeNoVarFiles: true,
+			},
+			want: []ExtRawProject{
+				{
+					Project: raw.Project{
+						Name: ptr("default"),
+						Dir:  ptr("."),
+					},
+					Component: ".",
+				},
+			},
+		},
+		{
+			component: Component{
+				Path: "test/novars",
+			},
+			options: Options{},
+			want:    nil,
+		},
+		{
+			component: Component{
+				Path:       "tofu",
+				VarFiles:   []VarFile{{Path: "tofu/dev.tfvars", Environment: "dev"}},
+				Extensions: []string{".tf", ".tofu"},
+			},
+			options: Options{
+				Autoplan:              true,
+				TerraformDistribution: "terraform",
+				ComponentDistributions: map[string]string{
+					"tofu": "opentofu",
+				},
+			},
+			want: []ExtRawProject{
+				{
+					Project: raw.Project{
+						Name: ptr("tofu-dev"),
+						Dir:  ptr("tofu"),
+						Autoplan: &raw.Autoplan{
+							Enabled: ptr(true),
+							WhenModified: []string{
+								"*.tf",
+								"*.tofu",
+								"dev.tfvars",
+							},
+						},
+					},
+					Component:             "tofu",
+					Environment:           "dev",
+					VarFiles:              []string{"tofu/dev.tfvars"},
+					TerraformDistribution: ptr("opentofu"),
+				},
+			},
+		},
+		{
+			// Local modules are planned through the components calling them
+			component: Component{
+				Path:     "modules/vpc",
+				IsModule: true,
+			},
+			options: Options{
+				Autoplan:          true,
+				IncludeNoVarFiles: true,
+			},
+		},
+		{
+			// Local modules with var files of their own are planned with them
+			component: Component{
+				Path:     "shared",
+				VarFiles: []VarFile{{Path: "shared/dev.tfvars", Environment: "dev"}},
+				IsModule: true,
+			},
+			options: Options{
+				IncludeNoVarFiles: true,
+			},
+			want: []ExtRawProject{
+				{
+					Project: raw.Project{
+						Name: ptr("shared-dev"),
+						Dir:  ptr("shared"),
+					},
+					Component:   "shared",
+					Environment: "dev",
+					VarFiles:    []string{"shared/dev.tfvars"},
 				},
 			},
 		},
@@ -82,6 +201,49 @@ func Test_AutoPlan(t *testing.T) {
 	}
 }
 
+// Tests the AutoPlan method watches each extension of the component's
+// configuration files
+func Test_AutoPlanExtensions(t *testing.T) {
+	t.Parallel()
+
+	want := &raw.Autoplan{
+		Enabled: ptr(true),
+		WhenModified: []string{
+			"*.tf",
+			"*.tf.json",
+			"env.tfvars",
+		},
+	}
+
+	got := new(ExtRawProject)
+
+	got.AutoPlan("env.tfvars", ".tf", ".tf.json")
+
+	if !cmp.Equal(got.Autoplan, want) {
+		t.Errorf(`AutoPlan()
+		diff %s`, cmp.Diff(got.Autoplan, want))
+	}
+}
+
+// Tests the Distribution method for a project only accepts known
+// distributions
+func Test_Distribution(t *testing.T) {
+	t.Parallel()
+
+	got := new(ExtRawProject)
+	if err := got.Distribution("opentofu"); err != nil {
+		t.Errorf("Distribution() error: %s", err)
+	}
+	if !cmp.Equal(got.TerraformDistribution, ptr("opentofu")) {
+		t.Errorf(`Distribution()
+		diff %s`, cmp.Diff(got.TerraformDistribution, ptr("opentofu")))
+	}
+
+	if err := got.Distribution("terragrunt"); err == nil {
+		t.Errorf("Distribution() expected error for unknown distribution")
+	}
+}
+
 // Tests the DefaultTerraformVersion method for a project
 func Test_DefaultTerraformVersion(t *testing.T) {
 	t.Parallel()
@@ -115,3 +277,109 @@ func Test_DefaultTerraformVersion(t *testing.T) {
 		}
 	}
 }
+
+// Tests the terraformVersion method for a project records the source of the
+// version it sets, and none for an invalid version.
+func Test_TerraformVersionSource(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		component  Component
+		options    Options
+		want       *string
+		wantSource string
+	}{
+		{
+			component: Component{Path: "db"},
+		},
+		{
+			component:  Component{Path: "db"},
+			options:    Options{DefaultTerraformVersion: "1.6.0", DefaultTerraformVersionSource: "terraform_version of root aws"},
+			want:       ptr("1.6.0"),
+			wantSource: "terraform_version of root aws",
+		},
+		{
+			component:  Component{Path: "db", TerraformVersion: "1.9.0", TerraformVersionFile: "db/.terraform-version"},
+			options:    Options{DefaultTerraformVersion: "1.6.0", DefaultTerraformVersionSource: "--terraform-version"},
+			want:       ptr("1.9.0"),
+			wantSource: "db/.terraform-version",
+		},
+		{
+			component: Component{Path: "db"},
+			options:   Options{DefaultTerraformVersion: "invalid", DefaultTerraformVersionSource: "--terraform-version"},
+			want:      new(string),
+		},
+	}
+
+	for _, tc := range tests {
+		got := new(ExtRawProject)
+		got.terraformVersion(tc.component, tc.options)
+
+		if !cmp.Equal(got.TerraformVersion, tc.want) || got.TerraformVersionSource != tc.wantSource {
+			t.Errorf("terraformVersion() = %v from %q, want %v from %q", got.TerraformVersion, got.TerraformVersionSource, tc.want, tc.wantSource)
+		}
+	}
+}
+
+// Tests the WorkflowsFrom function for a component with backend configs, and
+// the projects of the component using them.
+func Test_WorkflowsFrom(t *testing.T) {
+	t.Parallel()
+
+	component := Component{
+		Path:     "test",
+		VarFiles: []VarFile{{Path: "test/dev.tfvars", Environment: "dev"}, {Path: "test/prod.tfvars", Environment: "prod"}},
+		BackendConfigs: map[string]string{
+			"dev": "test/backend/dev.hcl",
+		},
+	}
+
+	want := map[string]raw.Workflow{
+		"test-dev": {
+			Plan: &raw.Stage{
+				Steps: []raw.Step{
+					{Map: map[string]map[string][]string{"init": {"extra_args": {"-backend-config=backend/dev.hcl"}}}},
+					{Map: map[string]map[string][]string{"plan": {"extra_args": {"-var-file=dev.tfvars"}}}},
+				},
+			},
+			Apply: &raw.Stage{
+				Steps: []raw.Step{
+					{Key: ptr("apply")},
+				},
+			},
+		},
+	}
+
+	got := WorkflowsFrom(component)
+	if !cmp.Equal(got, want) {
+		t.Errorf(`WorkflowsFrom()
+		diff %s`, cmp.Diff(got, want))
+	}
+
+	projects, err := ProjectsFrom(component, Options{})
+	if err != nil {
+		t.Fatalf("ProjectsFrom() error: %s", err)
+	}
+	if projects[0].Workflow == nil || *projects[0].Workflow != "test-dev" {
+		t.Errorf("ProjectsFrom() workflow = %v, want test-dev", projects[0].Workflow)
+	}
+	if projects[1].Workflow != nil {
+		t.Errorf("ProjectsFrom() workflow = %v, want nil", *projects[1].Workflow)
+	}
+
+	// Changing the backend config triggers a plan of its project
+	projects, err = ProjectsFrom(component, Options{Autoplan: true})
+	if err != nil {
+		t.Fatalf("ProjectsFrom() error: %s", err)
+	}
+	wantWhenModified := [][]string{
+		{"*.tf", "dev.tfvars", "backend/dev.hcl"},
+		{"*.tf", "prod.tfvars"},
+	}
+	for i, p := range projects {
+		if !cmp.Equal(p.Autoplan.WhenModified, wantWhenModified[i]) {
+			t.Errorf(`ProjectsFrom() when_modified
+			diff %s`, cmp.Diff(p.Autoplan.WhenModified, wantWhenModified[i]))
+		}
+	}
+}
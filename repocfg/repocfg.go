@@ -1,32 +1,177 @@
 package repocfg
 
 import (
+	"bytes"
 	"fmt"
+	"reflect"
 
 	"github.com/runatlantis/atlantis/server/core/config/raw"
-	"gopkg.in/yaml.v2"
+	"github.com/runatlantis/atlantis/server/core/config/valid"
+	"gopkg.in/yaml.v3"
 )
 
 var ErrNoExistingConfig = fmt.Errorf("no existing config found")
 
+const (
+	TERRAFORM_DISTRIBUTION = "terraform"
+	OPENTOFU_DISTRIBUTION  = "opentofu"
+
+	REPO_LOCKS_ON_PLAN  = "on_plan"
+	REPO_LOCKS_ON_APPLY = "on_apply"
+	REPO_LOCKS_DISABLED = "disabled"
+)
+
 // Options represents the top-level configuration for a new Atlantis RepoCfg
 type Options struct {
 	Automerge               bool
 	Autoplan                bool
 	DefaultTerraformVersion string
+	IncludeNoVarFiles       bool
 	Parallel                bool
 	UseWorkspaces           bool
+
+	// DefaultTerraformVersionSource is where DefaultTerraformVersion was
+	// given, e.g. "--terraform-version".
+	DefaultTerraformVersionSource string
+
+	// TerraformDistribution is the distribution Atlantis runs for every
+	// project, e.g. "opentofu". Atlantis' default is used if empty.
+	TerraformDistribution string
+
+	// ComponentDistributions overrides TerraformDistribution for the
+	// projects of the components with the given paths.
+	ComponentDistributions map[string]string
+
+	// Policies set the requirements, autoplan and branch of the projects by
+	// their environment, see EnvironmentPolicy.
+	Policies []EnvironmentPolicy
+
+	// ChangedFiles, if not nil, limits the projects to those affected by the
+	// changed files, relative to the repo root. An empty slice leaves no
+	// projects.
+	ChangedFiles []string
+
+	// GlobalCfg is the Atlantis server side config the generated config is
+	// validated against for RepoID, see ExtRawRepoCfg.Validate. A config
+	// allowing every setting is used if nil.
+	GlobalCfg *valid.GlobalCfg
+	RepoID    string
+
+	// The repo-level settings below are omitted from the generated config if
+	// unset, leaving Atlantis' defaults.
+	AbortOnExecutionOrderFail bool
+	AllowedRegexpPrefixes     []string
+	AutoDiscoverMode          string
+	DeleteSourceBranchOnMerge bool
+	EmojiReaction             string
+	ParallelPolicyCheck       bool
+
+	// RepoLocksMode is when Atlantis locks projects: "on_plan", "on_apply"
+	// or "disabled".
+	RepoLocksMode string
+
+	// ProvenanceComments comments each project with the component and var
+	// file it was generated from.
+	ProvenanceComments bool
+}
+
+// distribution returns the Terraform distribution for a component's projects
+func (opts Options) distribution(c Component) string {
+	if d, ok := opts.ComponentDistributions[c.Path]; ok {
+		return d
+	}
+	return opts.TerraformDistribution
+}
+
+// terraformVersion returns the Terraform version for a component's projects
+func (opts Options) terraformVersion(c Component) string {
+	if c.TerraformVersion != "" {
+		return c.TerraformVersion
+	}
+	return opts.DefaultTerraformVersion
+}
+
+// terraformVersionSource returns where the Terraform version for a
+// component's projects was given, its version file or the default's source.
+func (opts Options) terraformVersionSource(c Component) string {
+	if c.TerraformVersion != "" {
+		return c.TerraformVersionFile
+	}
+	return opts.DefaultTerraformVersionSource
 }
 
 // Component represents a Terraform component and its associated Terraform variable files
 type Component struct {
 	Path     string
-	VarFiles []string
+	VarFiles []VarFile
+
+	// BackendConfigs maps an environment name to the backend config file used
+	// to initialise the component for that environment.
+	BackendConfigs map[string]string
+
+	// Extensions are the extensions of the configuration files in the
+	// component, e.g. ".tf" and ".tofu". Defaults to ".tf" if empty.
+	Extensions []string
+
+	// Modules are the directories of the local modules the component calls,
+	// relative to the repo root.
+	Modules []string
+
+	// IsModule is set if another component calls the component as a local
+	// module, in which case it has no default project without variable
+	// files, see Options.IncludeNoVarFiles.
+	IsModule bool
+
+	// TerraformVersion is the version pinned for the component by a version
+	// file, e.g. .terraform-version, overriding the default version.
+	TerraformVersion string
+
+	// TerraformVersionFile is the version file TerraformVersion was read
+	// from.
+	TerraformVersionFile string
+}
+
+// VarFile represents a Terraform variable file and the environment it
+// configures
+type VarFile struct {
+	Path        string
+	Environment string
+
+	// Annotations are the directives in the variable file controlling the
+	// project generated from it.
+	Annotations Annotations
+}
+
+// environment returns the environment name of the variable file, falling back
+// to the variable file name without its extension if it has none.
+func (v VarFile) environment() string {
+	if v.Environment != "" {
+		return v.Environment
+	}
+	return pathWithoutExtension(v.Path)
 }
 
 // ExtRawRepoCfg is an embedded type for a raw.RepoCfg
 type ExtRawRepoCfg struct {
 	raw.RepoCfg `yaml:",inline"`
+
+	// Projects shadows raw.RepoCfg.Projects to keep the fields of each
+	// ExtRawProject that raw.Project does not have. They are marshalled as
+	// `projects` by MarshalYAML.
+	Projects []ExtRawProject `yaml:"-"`
+
+	// ParallelPolicyCheck and RepoLocks are not yet supported by raw.RepoCfg.
+	ParallelPolicyCheck *bool      `yaml:"parallel_policy_check,omitempty"`
+	RepoLocks           *RepoLocks `yaml:"repo_locks,omitempty"`
+
+	// Header is written as a comment at the top of the marshalled config,
+	// e.g. to warn it is generated.
+	Header string `yaml:"-"`
+}
+
+// RepoLocks represents when Atlantis locks the projects of the repo
+type RepoLocks struct {
+	Mode string `yaml:"mode"`
 }
 
 // NewRepoCfg returns a new Atlantis RepoCfg from a slice of components
@@ -43,31 +188,145 @@ func NewRepoCfg(components []Component, opts Options) (*ExtRawRepoCfg, error) {
 		},
 	}
 
-	var projects []raw.Project
+	if err := repoCfg.repoSettings(opts); err != nil {
+		return nil, err
+	}
+
+	var projects []ExtRawProject
 	for _, c := range components {
 		generated, err := ProjectsFrom(c, opts)
 		if err != nil {
 			return nil, fmt.Errorf("failed while creating projects with component %+v: %w", c, err)
 		}
 
-		for _, p := range generated {
-			projects = append(projects, p.Project)
+		projects = append(projects, generated...)
+
+		for name, w := range WorkflowsFrom(c) {
+			if repoCfg.Workflows == nil {
+				repoCfg.Workflows = map[string]raw.Workflow{}
+			}
+			repoCfg.Workflows[name] = w
 		}
 	}
 
 	repoCfg.Projects = append(repoCfg.Projects, projects...)
 
+	if opts.ChangedFiles != nil {
+		err := repoCfg.filterChanged(components, opts.ChangedFiles)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := repoCfg.Validate(opts); err != nil {
+		return nil, err
+	}
+
 	return repoCfg, nil
 }
 
+// repoSettings sets the repo-level settings which are set in opts
+func (rc *ExtRawRepoCfg) repoSettings(opts Options) error {
+	if opts.AbortOnExecutionOrderFail {
+		rc.AbortOnExcecutionOrderFail = ptr(true)
+	}
+	if len(opts.AllowedRegexpPrefixes) > 0 {
+		rc.AllowedRegexpPrefixes = opts.AllowedRegexpPrefixes
+	}
+	switch mode := valid.AutoDiscoverMode(opts.AutoDiscoverMode); mode {
+	case "":
+	case valid.AutoDiscoverAutoMode, valid.AutoDiscoverEnabledMode, valid.AutoDiscoverDisabledMode:
+		rc.AutoDiscover = &raw.AutoDiscover{Mode: ptr(mode)}
+	default:
+		return fmt.Errorf("unknown autodiscover mode %q, must be one of: %s, %s, %s", mode, valid.AutoDiscoverAutoMode, valid.AutoDiscoverEnabledMode, valid.AutoDiscoverDisabledMode)
+	}
+	if opts.DeleteSourceBranchOnMerge {
+		rc.DeleteSourceBranchOnMerge = ptr(true)
+	}
+	if opts.EmojiReaction != "" {
+		rc.EmojiReaction = ptr(opts.EmojiReaction)
+	}
+	if opts.ParallelPolicyCheck {
+		rc.ParallelPolicyCheck = ptr(true)
+	}
+
+	switch opts.RepoLocksMode {
+	case "":
+	case REPO_LOCKS_ON_PLAN, REPO_LOCKS_ON_APPLY, REPO_LOCKS_DISABLED:
+		rc.RepoLocks = &RepoLocks{Mode: opts.RepoLocksMode}
+	default:
+		return fmt.Errorf("unknown repo locks mode %q, must be one of: %s, %s, %s", opts.RepoLocksMode, REPO_LOCKS_ON_PLAN, REPO_LOCKS_ON_APPLY, REPO_LOCKS_DISABLED)
+	}
+
+	return nil
+}
+
+// Marshal returns the YAML of the repo config, indented like the Atlantis
+// documentation.
+func (rc *ExtRawRepoCfg) Marshal() ([]byte, error) {
+	var b bytes.Buffer
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	if err := enc.Encode(rc); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// MarshalYAML marshals every field of the repo config that is set, in the
+// order of the Atlantis documentation, as raw.RepoCfg cannot marshal the
+// extended projects or the fields it does not have. The header and the
+// comment of each project are kept as YAML comments.
 func (rc *ExtRawRepoCfg) MarshalYAML() (interface{}, error) {
-	m := yaml.MapSlice{
-		{Key: "version", Value: rc.Version},
-		{Key: "automerge", Value: rc.Automerge},
-		{Key: "parallel_plan", Value: rc.ParallelPlan},
-		{Key: "parallel_apply", Value: rc.ParallelApply},
+	m := &yaml.Node{Kind: yaml.MappingNode, HeadComment: rc.Header}
+
+	var err error
+	add := func(key string, value interface{}, set bool) {
+		if !set || err != nil {
+			return
+		}
+		v := &yaml.Node{}
+		if err = v.Encode(value); err != nil {
+			err = fmt.Errorf("marshalling %s: %w", key, err)
+			return
+		}
+		m.Content = append(m.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, v)
+	}
+
+	add("version", rc.Version, rc.Version != nil)
+	add("automerge", rc.Automerge, rc.Automerge != nil)
+	add("autodiscover", rc.AutoDiscover, rc.AutoDiscover != nil)
+	add("delete_source_branch_on_merge", rc.DeleteSourceBranchOnMerge, rc.DeleteSourceBranchOnMerge != nil)
+	add("parallel_plan", rc.ParallelPlan, rc.ParallelPlan != nil)
+	add("parallel_apply", rc.ParallelApply, rc.ParallelApply != nil)
+	add("parallel_policy_check", rc.ParallelPolicyCheck, rc.ParallelPolicyCheck != nil)
+	add("abort_on_execution_order_fail", rc.AbortOnExcecutionOrderFail, rc.AbortOnExcecutionOrderFail != nil)
+	add("repo_locks", rc.RepoLocks, rc.RepoLocks != nil)
+	add("emoji_reaction", rc.EmojiReaction, rc.EmojiReaction != nil)
+	add("allowed_regexp_prefixes", rc.AllowedRegexpPrefixes, len(rc.AllowedRegexpPrefixes) > 0)
+	if err != nil {
+		return nil, err
+	}
+
+	// Projects are always emitted, even if there are none.
+	projects := &yaml.Node{Kind: yaml.SequenceNode}
+	for _, p := range rc.Projects {
+		v := &yaml.Node{}
+		if err := v.Encode(p); err != nil {
+			return nil, fmt.Errorf("marshalling project %s: %w", *p.Name, err)
+		}
+		v.HeadComment = p.Comment
+		projects.Content = append(projects.Content, v)
+	}
+	m.Content = append(m.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "projects"}, projects)
 
-		{Key: "projects", Value: rc.Projects},
+	add("workflows", rc.Workflows, len(rc.Workflows) > 0)
+	add("policies", rc.PolicySets, !reflect.ValueOf(rc.PolicySets).IsZero())
+	if err != nil {
+		return nil, err
 	}
 
 	return m, nil
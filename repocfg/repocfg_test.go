@@ -7,12 +7,15 @@ import (
 	"github.com/runatlantis/atlantis/server/core/config/raw"
 )
 
+// Tests NewRepoCfg generates a project per environment of each component,
+// planned with all of the environment's var files.
 func Test_NewFrom(t *testing.T) {
 	t.Parallel()
 
 	tests := []struct {
 		name       string
 		components []Component
+		opts       Options
 		want       *ExtRawRepoCfg
 	}{
 		{
@@ -20,7 +23,7 @@ func Test_NewFrom(t *testing.T) {
 			components: []Component{
 				{
 					Path:     "test",
-					VarFiles: []string{"test/vars/dev.tfvars", "test/vars/nested/stg.tfvars"},
+					VarFiles: []VarFile{{Path: "test/vars/dev.tfvars", Environment: "dev"}, {Path: "test/vars/nested/stg.tfvars", Environment: "stg"}},
 				},
 			},
 			want: &ExtRawRepoCfg{
@@ -29,15 +32,92 @@ func Test_NewFrom(t *testing.T) {
 					Automerge:     ptr(false),
 					ParallelPlan:  ptr(false),
 					ParallelApply: ptr(false),
-					Projects: []raw.Project{
-						{
+				},
+				Projects: []ExtRawProject{
+					{
+						Project: raw.Project{
 							Name: ptr("test-dev"),
 							Dir:  ptr("test"),
 						},
-						{
+						Component:   "test",
+						Environment: "dev",
+						VarFiles:    []string{"test/vars/dev.tfvars"},
+					},
+					{
+						Project: raw.Project{
 							Name: ptr("test-stg"),
 							Dir:  ptr("test"),
 						},
+						Component:   "test",
+						Environment: "stg",
+						VarFiles:    []string{"test/vars/nested/stg.tfvars"},
+					},
+				},
+			},
+		},
+		{
+			name: "SeveralVarFilesPerEnvironment",
+			components: []Component{
+				{
+					Path: "test",
+					VarFiles: []VarFile{
+						{Path: "test/envs/dev/terraform.tfvars", Environment: "dev"},
+						{Path: "test/envs/dev/extra.tfvars", Environment: "dev", Annotations: Annotations{Workspace: "development"}},
+						{Path: "test/envs/prd/terraform.tfvars", Environment: "prd"},
+					},
+					BackendConfigs: map[string]string{"dev": "test/backend/dev.hcl"},
+				},
+			},
+			opts: Options{Autoplan: true, UseWorkspaces: true},
+			want: &ExtRawRepoCfg{
+				RepoCfg: raw.RepoCfg{
+					Version:       ptr(3),
+					Automerge:     ptr(false),
+					ParallelPlan:  ptr(false),
+					ParallelApply: ptr(false),
+					Workflows: map[string]raw.Workflow{
+						"test-dev": {
+							Plan: &raw.Stage{
+								Steps: []raw.Step{
+									{Map: map[string]map[string][]string{"init": {"extra_args": {"-backend-config=backend/dev.hcl"}}}},
+									{Map: map[string]map[string][]string{"plan": {"extra_args": {"-var-file=envs/dev/terraform.tfvars", "-var-file=envs/dev/extra.tfvars"}}}},
+								},
+							},
+							Apply: &raw.Stage{
+								Steps: []raw.Step{{Key: ptr("apply")}},
+							},
+						},
+					},
+				},
+				Projects: []ExtRawProject{
+					{
+						Project: raw.Project{
+							Name:      ptr("test-dev"),
+							Dir:       ptr("test"),
+							Workspace: ptr("development"),
+							Workflow:  ptr("test-dev"),
+							Autoplan: &raw.Autoplan{
+								Enabled:      ptr(true),
+								WhenModified: []string{"*.tf", "envs/dev/terraform.tfvars", "envs/dev/extra.tfvars", "backend/dev.hcl"},
+							},
+						},
+						Component:   "test",
+						Environment: "dev",
+						VarFiles:    []string{"test/envs/dev/terraform.tfvars", "test/envs/dev/extra.tfvars"},
+					},
+					{
+						Project: raw.Project{
+							Name:      ptr("test-prd"),
+							Dir:       ptr("test"),
+							Workspace: ptr("prd"),
+							Autoplan: &raw.Autoplan{
+								Enabled:      ptr(true),
+								WhenModified: []string{"*.tf", "envs/prd/terraform.tfvars"},
+							},
+						},
+						Component:   "test",
+						Environment: "prd",
+						VarFiles:    []string{"test/envs/prd/terraform.tfvars"},
 					},
 				},
 			},
@@ -45,10 +125,154 @@ func Test_NewFrom(t *testing.T) {
 	}
 
 	for _, tc := range tests {
-		got, _ := NewRepoCfg(tc.components, Options{})
+		got, _ := NewRepoCfg(tc.components, tc.opts)
 		if !cmp.Equal(got, tc.want) {
 			t.Errorf(`NewFrom()
 				diff %s`, cmp.Diff(got, tc.want))
 		}
 	}
 }
+
+// Tests MarshalYAML emits every repo-level setting that is set, in a stable
+// order, and omits those that are not.
+func Test_MarshalYAML(t *testing.T) {
+	t.Parallel()
+
+	components := []Component{
+		{Path: "test", VarFiles: []VarFile{{Path: "test/dev.tfvars", Environment: "dev"}}},
+	}
+
+	tests := []struct {
+		name    string
+		options Options
+		want    string
+	}{
+		{
+			name: "Defaults",
+			want: `version: 3
+automerge: false
+parallel_plan: false
+parallel_apply: false
+projects:
+  - name: test-dev
+    dir: test
+`,
+		},
+		{
+			name: "RepoSettings",
+			options: Options{
+				AbortOnExecutionOrderFail: true,
+				AllowedRegexpPrefixes:     []string{"dev", "stg"},
+				AutoDiscoverMode:          "disabled",
+				DeleteSourceBranchOnMerge: true,
+				EmojiReaction:             "eyes",
+				ParallelPolicyCheck:       true,
+				RepoLocksMode:             REPO_LOCKS_ON_APPLY,
+			},
+			want: `version: 3
+automerge: false
+autodiscover:
+  mode: disabled
+delete_source_branch_on_merge: true
+parallel_plan: false
+parallel_apply: false
+parallel_policy_check: true
+abort_on_execution_order_fail: true
+repo_locks:
+  mode: on_apply
+emoji_reaction: eyes
+allowed_regexp_prefixes:
+  - dev
+  - stg
+projects:
+  - name: test-dev
+    dir: test
+`,
+		},
+	}
+
+	for _, tc := range tests {
+		cfg, err := NewRepoCfg(components, tc.options)
+		if err != nil {
+			t.Fatalf("NewRepoCfg() error: %s", err)
+		}
+
+		got, err := cfg.Marshal()
+		if err != nil {
+			t.Fatalf("MarshalYAML() error: %s", err)
+		}
+
+		if string(got) != tc.want {
+			t.Errorf(`MarshalYAML() %s
+			diff %s`, tc.name, cmp.Diff(string(got), tc.want))
+		}
+	}
+
+	// Fields set on the embedded raw.RepoCfg are kept too
+	cfg := &ExtRawRepoCfg{RepoCfg: raw.RepoCfg{PolicySets: raw.PolicySets{Version: ptr("0.46.0")}}}
+	got, err := cfg.Marshal()
+	if err != nil {
+		t.Fatalf("MarshalYAML() error: %s", err)
+	}
+	want := "projects: []\npolicies:\n  conftest_version: 0.46.0\n  policy_sets: []\n"
+	if string(got) != want {
+		t.Errorf(`MarshalYAML()
+		diff %s`, cmp.Diff(string(got), want))
+	}
+}
+
+// Tests NewRepoCfg rejects unknown repo-level modes
+func Test_NewRepoCfgModes(t *testing.T) {
+	t.Parallel()
+
+	for _, opts := range []Options{{AutoDiscoverMode: "sometimes"}, {RepoLocksMode: "always"}} {
+		if _, err := NewRepoCfg(nil, opts); err == nil {
+			t.Errorf("NewRepoCfg(%+v) expected error for unknown mode", opts)
+		}
+	}
+}
+
+// Tests the header and the provenance comment of each project are written as
+// YAML comments, and ignored by Atlantis.
+func Test_MarshalComments(t *testing.T) {
+	t.Parallel()
+
+	components := []Component{
+		{Path: "test", VarFiles: []VarFile{{Path: "test/dev.tfvars", Environment: "dev"}}},
+		{Path: "novars"},
+	}
+
+	cfg, err := NewRepoCfg(components, Options{IncludeNoVarFiles: true, ProvenanceComments: true})
+	if err != nil {
+		t.Fatalf("NewRepoCfg() error: %s", err)
+	}
+	cfg.Header = "Code generated by test. DO NOT EDIT."
+
+	want := `# Code generated by test. DO NOT EDIT.
+version: 3
+automerge: false
+parallel_plan: false
+parallel_apply: false
+projects:
+  # generated from component test and var file test/dev.tfvars
+  - name: test-dev
+    dir: test
+  # generated from component novars without var files
+  - name: novars
+    dir: novars
+`
+
+	got, err := cfg.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal() error: %s", err)
+	}
+
+	if string(got) != want {
+		t.Errorf(`Marshal()
+		diff %s`, cmp.Diff(string(got), want))
+	}
+
+	if err := cfg.Validate(Options{}); err != nil {
+		t.Errorf("Validate() error: %s", err)
+	}
+}
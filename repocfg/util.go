@@ -9,9 +9,7 @@ import (
 func ptr[T any](v T) *T { return &v }
 
 // friendlyName creates a contextual name used for Atlantis projects
-func friendlyName(path, varFile string) string {
-	environment := pathWithoutExtension(filepath.Base(varFile))
-
+func friendlyName(path, environment string) string {
 	// avoid constructing a joined path if the context is the current directory
 	if filepath.Base(path) == "." {
 		return environment
@@ -26,3 +24,14 @@ func friendlyName(path, varFile string) string {
 func pathWithoutExtension(path string) string {
 	return strings.TrimSuffix(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)), filepath.Base(path))
 }
+
+// relativeTo returns the path relative to the base directory, as Atlantis
+// runs workflow steps from within the project directory. If the path cannot
+// be made relative it is returned as is.
+func relativeTo(base, path string) string {
+	rel, err := filepath.Rel(base, path)
+	if err != nil {
+		return path
+	}
+	return rel
+}
@@ -32,7 +32,7 @@ func Test_FriendlyName(t *testing.T) {
 	t.Parallel()
 
 	path := "my/path/to/some/terraform/component"
-	environment := "my/path/to/some/terraform/component/dev.tfvars"
+	environment := "dev"
 
 	want := "my-path-to-some-terraform-component-dev"
 	got := friendlyName(path, environment)
@@ -0,0 +1,51 @@
+package repocfg
+
+import (
+	"fmt"
+
+	"github.com/runatlantis/atlantis/server/core/config"
+	"github.com/runatlantis/atlantis/server/core/config/valid"
+)
+
+// permissiveGlobalCfg is the server side config used for validation when
+// none is given, allowing the repo config to override every setting.
+var permissiveGlobalCfg = valid.NewGlobalCfgFromArgs(valid.GlobalCfgArgs{
+	AllowAllRepoSettings: true,
+})
+
+// Validate marshals the repo config and parses it back the way the Atlantis
+// server parses an atlantis.yaml, returning the error Atlantis would reject
+// it with, e.g. for duplicate project names or overrides the server side
+// config does not allow.
+//
+// It is validated against the server side config in opts, or one allowing
+// every setting if nil. Fields of ExtRawRepoCfg and ExtRawProject the pinned
+// version of Atlantis does not know, e.g. terraform_distribution, are not
+// validated.
+func (rc *ExtRawRepoCfg) Validate(opts Options) error {
+	globalCfg := permissiveGlobalCfg
+	if opts.GlobalCfg != nil {
+		globalCfg = *opts.GlobalCfg
+	}
+
+	// Atlantis rejects unknown fields, so the fields it does not know are
+	// removed from a copy of the config.
+	compat := *rc
+	compat.ParallelPolicyCheck = nil
+	compat.RepoLocks = nil
+	compat.Projects = make([]ExtRawProject, len(rc.Projects))
+	for i, p := range rc.Projects {
+		compat.Projects[i] = ExtRawProject{Project: p.Project}
+	}
+
+	b, err := compat.Marshal()
+	if err != nil {
+		return fmt.Errorf("marshalling repo config for validation: %w", err)
+	}
+
+	parser := &config.ParserValidator{}
+	if _, err := parser.ParseRepoCfgData(b, globalCfg, opts.RepoID, ""); err != nil {
+		return fmt.Errorf("atlantis would reject the generated config: %w", err)
+	}
+	return nil
+}
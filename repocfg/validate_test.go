@@ -0,0 +1,68 @@
+package repocfg
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/core/config/valid"
+)
+
+// Tests NewRepoCfg rejects configs Atlantis would reject, with the reason
+// Atlantis gives.
+func Test_NewRepoCfgValidate(t *testing.T) {
+	t.Parallel()
+
+	strict := valid.NewGlobalCfgFromArgs(valid.GlobalCfgArgs{})
+
+	tests := []struct {
+		name       string
+		components []Component
+		options    Options
+		wantErr    string
+	}{
+		{
+			name: "Valid",
+			components: []Component{
+				{Path: "db", VarFiles: []VarFile{{Path: "db/dev.tfvars", Environment: "dev"}}},
+			},
+			options: Options{TerraformDistribution: OPENTOFU_DISTRIBUTION, UseWorkspaces: true},
+		},
+		{
+			name: "DuplicateNames",
+			components: []Component{
+				{Path: "a-b", VarFiles: []VarFile{{Path: "a-b/dev.tfvars", Environment: "dev"}}},
+				{Path: "a/b", VarFiles: []VarFile{{Path: "a/b/dev.tfvars", Environment: "dev"}}},
+			},
+			wantErr: `found two or more projects with name "a-b-dev"`,
+		},
+		{
+			name: "OverrideNotAllowed",
+			components: []Component{
+				{
+					Path:           "db",
+					VarFiles:       []VarFile{{Path: "db/dev.tfvars", Environment: "dev"}},
+					BackendConfigs: map[string]string{"dev": "db/backend/dev.hcl"},
+				},
+			},
+			options: Options{GlobalCfg: &strict, RepoID: "github.com/org/repo"},
+			wantErr: "repo config not allowed to set 'workflow' key",
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := NewRepoCfg(tc.components, tc.options)
+			switch {
+			case tc.wantErr == "" && err != nil:
+				t.Errorf("NewRepoCfg() error: %s", err)
+			case tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)):
+				t.Errorf(`NewRepoCfg()
+				got error %v
+				want error containing %q`, err, tc.wantErr)
+			}
+		})
+	}
+}